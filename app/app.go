@@ -3,6 +3,7 @@ package app
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"time"
@@ -17,12 +18,27 @@ import (
 )
 
 type App struct {
-	envInfo env.Info
-	logger  xlogger.Logger
-	svr     Server
+	envInfo   env.Info
+	logger    xlogger.Logger
+	svr       Server
+	admin     *http.ServeMux
+	adminAddr string
+	// adminOn 是否通过 WithAdminAddr / HandleAdmin 启用了管理端口, 生产环境只在启用后监听
+	adminOn  bool
+	adminSvr *http.Server
 }
 
-func New(envInfo env.Info, logger xlogger.Logger, svr Server) *App {
+type Option func(a *App)
+
+// WithAdminAddr 设置管理端口的监听地址并启用管理端口, 默认非生产环境为 :6789, 生产环境为 127.0.0.1:6789
+func WithAdminAddr(addr string) Option {
+	return func(a *App) {
+		a.adminAddr = addr
+		a.adminOn = true
+	}
+}
+
+func New(envInfo env.Info, logger xlogger.Logger, svr Server, opts ...Option) *App {
 	if !envInfo.Envv.Valid() {
 		panic(fmt.Sprintf("env is not valid: %s", envInfo.Envv))
 	}
@@ -33,20 +49,36 @@ func New(envInfo env.Info, logger xlogger.Logger, svr Server) *App {
 		panic("svr is nil")
 	}
 
-	return &App{
-		envInfo: envInfo,
-		logger:  logger,
-		svr:     svr,
+	a := &App{
+		envInfo:   envInfo,
+		logger:    logger,
+		svr:       svr,
+		admin:     http.NewServeMux(),
+		adminAddr: ":6789",
+	}
+	if envInfo.Envv.IsProduction() {
+		a.adminAddr = "127.0.0.1:6789"
+	} else {
+		// 非生产环境管理端口未匹配的路径交给 DefaultServeMux 处理, 保留 pprof
+		a.admin.Handle("/", http.DefaultServeMux)
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// HandleAdmin 在管理端口上注册 handler 并启用管理端口, 如 HandleAdmin("/debug/log/level", xlogger.LevelsOf(logger));
+// 管理端口在非生产环境下总是启动, 生产环境只在调用 HandleAdmin 或 WithAdminAddr 后启动
+func (a *App) HandleAdmin(pattern string, handler http.Handler) {
+	a.admin.Handle(pattern, handler)
+	a.adminOn = true
 }
 
+// Run 启动管理端口和 svr, 收到退出信号后关闭; 生产环境启用的管理端口监听失败时返回错误
 func (a *App) Run() error {
-	if !a.envInfo.Envv.IsProduction() {
-		go func() {
-			if err := http.ListenAndServe(":6789", nil); err != nil {
-				a.logger.Warn("Run http.ListenAndServe", "err", err)
-			}
-		}()
+	if err := a.serveAdmin(); err != nil {
+		return err
 	}
 
 	go func() {
@@ -62,9 +94,36 @@ func (a *App) Run() error {
 	return nil
 }
 
+// serveAdmin 按环境和选项启动管理端口
+func (a *App) serveAdmin() error {
+	if a.envInfo.Envv.IsProduction() && !a.adminOn {
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", a.adminAddr)
+	if err != nil {
+		a.logger.Error("Run admin net.Listen", "addr", a.adminAddr, "err", err)
+		if a.adminOn {
+			return fmt.Errorf("listen admin %s: %w", a.adminAddr, err)
+		}
+		// 非生产环境默认的管理端口可能被本地的其他服务占用, 不影响启动
+		return nil
+	}
+	a.adminSvr = &http.Server{Handler: a.admin}
+	go func() {
+		if err := a.adminSvr.Serve(ln); err != nil && err != http.ErrServerClosed {
+			a.logger.Error("Run admin Serve", "err", err)
+		}
+	}()
+	return nil
+}
+
 func (a *App) Close() {
 	ctx, fn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer fn()
 	_ = a.svr.Close(ctx)
-	fn()
+	if a.adminSvr != nil {
+		_ = a.adminSvr.Shutdown(ctx)
+	}
 	a.logger.Info("server closed")
 }
@@ -2,10 +2,12 @@ package app
 
 import (
 	"context"
+	"net/http"
 	"testing"
 
 	"skuld/env"
 	"skuld/xlogger"
+	"skuld/xlogger/xloggertest"
 )
 
 type mockServer struct{}
@@ -14,18 +16,6 @@ func (m mockServer) Run() error { return nil }
 
 func (m mockServer) Close(ctx context.Context) error { return nil }
 
-type mockLogger struct{}
-
-func (m mockLogger) Debug(msg string, kvs ...interface{}) {}
-
-func (m mockLogger) Info(msg string, kvs ...interface{}) {}
-
-func (m mockLogger) Warn(msg string, kvs ...interface{}) {}
-
-func (m mockLogger) Error(msg string, kvs ...interface{}) {}
-
-func (m mockLogger) Fatal(msg string, kvs ...interface{}) {}
-
 func TestNew(t *testing.T) {
 	type testCase struct {
 		name        string
@@ -35,7 +25,7 @@ func TestNew(t *testing.T) {
 		expectPanic bool
 	}
 
-	logger := &mockLogger{}
+	logger := xloggertest.NewT(t)
 	svr := &mockServer{}
 
 	testTable := []testCase{
@@ -84,3 +74,56 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestAdminAddr(t *testing.T) {
+	logger := xloggertest.NewT(t)
+	svr := &mockServer{}
+
+	if a := New(env.NewInfo("", "", 0, env.New("local")), logger, svr); a.adminAddr != ":6789" {
+		t.Errorf("expect :6789, but get %s", a.adminAddr)
+	}
+	if a := New(env.NewInfo("", "", 0, env.New("production")), logger, svr); a.adminAddr != "127.0.0.1:6789" {
+		t.Errorf("expect 127.0.0.1:6789, but get %s", a.adminAddr)
+	}
+	if a := New(env.NewInfo("", "", 0, env.New("production")), logger, svr, WithAdminAddr("10.0.0.1:6789")); a.adminAddr != "10.0.0.1:6789" {
+		t.Errorf("expect 10.0.0.1:6789, but get %s", a.adminAddr)
+	}
+}
+
+func TestAdminProduction(t *testing.T) {
+	logger := xloggertest.NewT(t)
+	svr := &mockServer{}
+	info := env.NewInfo("", "", 0, env.New("production"))
+
+	// 生产环境默认不监听管理端口
+	a := New(info, logger, svr)
+	if err := a.serveAdmin(); err != nil {
+		t.Fatal(err)
+	}
+	if a.adminSvr != nil {
+		t.Fatal("expect admin server not started in production by default")
+	}
+
+	// 启用后监听, 并在 Close 时关闭
+	a = New(info, logger, svr, WithAdminAddr("127.0.0.1:0"))
+	a.HandleAdmin("/ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if err := a.serveAdmin(); err != nil {
+		t.Fatal(err)
+	}
+	if a.adminSvr == nil {
+		t.Fatal("expect admin server started")
+	}
+	a.Close()
+	if err := a.adminSvr.ListenAndServe(); err != http.ErrServerClosed {
+		t.Errorf("expect admin server closed, but get %v", err)
+	}
+}
+
+func TestAdminListenError(t *testing.T) {
+	logger := xloggertest.NewT(t)
+	logger.ExpectError("Run admin net.Listen")
+	a := New(env.NewInfo("", "", 0, env.New("production")), logger, &mockServer{}, WithAdminAddr("127.0.0.1:-1"))
+	if err := a.serveAdmin(); err == nil {
+		t.Error("expect listen error returned")
+	}
+}
@@ -11,7 +11,7 @@ import (
 
 // ListenSignal 监听信号
 func listenSignal() os.Signal {
-	ch := make(chan os.Signal)
+	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR1)
 	return <-ch
 }
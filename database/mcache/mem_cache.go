@@ -8,10 +8,25 @@ import (
 // Item 内存缓存单个实例
 type Item interface{}
 
+// Recorder 记录每次 Get 是否命中, 可以实现该接口将命中率导出为监控指标
+type Recorder interface {
+	Record(hit bool)
+}
+
 // MemCache 内存缓存结构
 type MemCache struct {
-	items map[string]Item
-	mu    sync.RWMutex
+	items    map[string]Item
+	mu       sync.RWMutex
+	recorder Recorder
+}
+
+type Option func(*MemCache)
+
+// WithRecorder 将每次 Get 是否命中交给 r
+func WithRecorder(r Recorder) Option {
+	return func(m *MemCache) {
+		m.recorder = r
+	}
 }
 
 // Set Add an item to the MemCache, replacing any existing item.
@@ -56,11 +71,13 @@ func (m *MemCache) Replace(k string, x interface{}) error {
 func (m *MemCache) Get(k string) (interface{}, bool) {
 	m.mu.RLock()
 	item, found := m.items[k]
+	m.mu.RUnlock()
+	if m.recorder != nil {
+		m.recorder.Record(found)
+	}
 	if !found {
-		m.mu.RUnlock()
 		return nil, false
 	}
-	m.mu.RUnlock()
 	return item, true
 }
 
@@ -110,6 +127,10 @@ func (m *MemCache) Flush() {
 }
 
 // NewMCache New Go MemCache
-func NewMCache() *MemCache {
-	return &MemCache{items: make(map[string]Item)}
+func NewMCache(opts ...Option) *MemCache {
+	m := &MemCache{items: make(map[string]Item)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
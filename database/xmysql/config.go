@@ -10,4 +10,8 @@ type Config struct {
 	Idle     int           `mapstructure:"idle"`
 	Open     int           `mapstructure:"open"`
 	IdleTime time.Duration `mapstructure:"idle_time"`
+	// SlowThreshold 慢查询阈值, 为 0 时使用 1 秒, 小于 0 时不记录慢查询
+	SlowThreshold time.Duration `mapstructure:"slow_threshold"`
+	// LogLevel sql 日志级别, silent / error / warn / info, 为空时使用 warn
+	LogLevel string `mapstructure:"log_level"`
 }
@@ -6,15 +6,93 @@ import (
 	"os"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
+	"skuld/xlogger"
 	"skuld/xorm"
 	"skuld/xsql"
 )
 
-func NewDB(c Config) xsql.DBItf {
+type Option func(*options)
+
+type options struct {
+	logger   xlogger.Logger
+	recorder Recorder
+	onOpen   []func(db *sql.DB) error
+	tracer   trace.TracerProvider
+}
+
+// Recorder 同时满足 xsql.Recorder 和 xorm.Recorder
+type Recorder interface {
+	Record(op string, d time.Duration, err error)
+}
+
+// WithLogger sql 日志写入 logger, 未设置时 NewORM 输出到标准输出, NewDB 不输出日志
+func WithLogger(logger xlogger.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithRecorder 将每条 sql 的操作类型, 耗时和结果交给 r, 用于导出监控指标
+func WithRecorder(r Recorder) Option {
+	return func(o *options) {
+		o.recorder = r
+	}
+}
+
+// WithOnOpen 连接池创建后调用 f, 如注册连接池状态的监控指标, f 返回的错误写入日志, 不影响连接池的使用
+func WithOnOpen(f func(db *sql.DB) error) Option {
+	return func(o *options) {
+		o.onOpen = append(o.onOpen, f)
+	}
+}
+
+// WithTracerProvider 为每条 sql 创建 span, 父节点来自调用时传入的 ctx
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracer = tp
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (o options) open(db *sql.DB) {
+	for _, f := range o.onOpen {
+		if err := f(db); err != nil {
+			if o.logger != nil {
+				o.logger.Warn("xmysql onOpen", "err", err)
+			} else {
+				log.Printf("xmysql onOpen: %v", err)
+			}
+		}
+	}
+}
+
+// slowThreshold 返回传给 xsql / xorm 日志的慢查询阈值, 其中 0 表示不记录慢查询
+func slowThreshold(c Config) time.Duration {
+	switch {
+	case c.SlowThreshold > 0:
+		return c.SlowThreshold
+	case c.SlowThreshold < 0:
+		return 0
+	default:
+		return time.Second
+	}
+}
+
+func NewDB(c Config, opts ...Option) xsql.DBItf {
+	o := newOptions(opts)
+
 	db, err := sql.Open("mysql", c.Source)
 	if err != nil {
 		panic(err)
@@ -23,21 +101,41 @@ func NewDB(c Config) xsql.DBItf {
 	db.SetConnMaxIdleTime(c.IdleTime)
 	db.SetMaxIdleConns(c.Idle)
 	db.SetMaxOpenConns(c.Open)
+	o.open(db)
 
-	return xsql.New(db)
+	var dbOpts []xsql.Option
+	if o.logger != nil {
+		dbOpts = append(dbOpts, xsql.WithLogger(o.logger, c.LogLevel, slowThreshold(c)))
+	}
+	if o.recorder != nil {
+		dbOpts = append(dbOpts, xsql.WithRecorder(o.recorder))
+	}
+	if o.tracer != nil {
+		dbOpts = append(dbOpts, xsql.WithTracerProvider(o.tracer))
+	}
+	return xsql.New(db, dbOpts...)
 }
 
-func NewORM(c Config) xorm.ORMItf {
-	var err error
-	orm, err := gorm.Open(mysql.Open(c.Source), &gorm.Config{
-		Logger: logger.New(
+func NewORM(c Config, opts ...Option) xorm.ORMItf {
+	o := newOptions(opts)
+
+	var ormLogger logger.Interface
+	if o.logger != nil {
+		ormLogger = xorm.NewLogger(o.logger, c.LogLevel, slowThreshold(c))
+	} else {
+		ormLogger = logger.New(
 			log.New(os.Stdout, "", log.LstdFlags),
 			logger.Config{
-				SlowThreshold:             time.Second,
+				SlowThreshold:             slowThreshold(c),
 				Colorful:                  false,
 				IgnoreRecordNotFoundError: true,
-				LogLevel:                  logger.Warn,
-			}),
+				LogLevel:                  xorm.ParseLogLevel(c.LogLevel),
+			})
+	}
+
+	var err error
+	orm, err := gorm.Open(mysql.Open(c.Source), &gorm.Config{
+		Logger: ormLogger,
 	})
 	if err != nil {
 		panic(err)
@@ -50,6 +148,18 @@ func NewORM(c Config) xorm.ORMItf {
 	rawdb.SetConnMaxIdleTime(c.IdleTime)
 	rawdb.SetMaxIdleConns(c.Idle)
 	rawdb.SetMaxOpenConns(c.Open)
+	o.open(rawdb)
+
+	if o.recorder != nil {
+		if err = orm.Use(xorm.NewRecorderPlugin(o.recorder)); err != nil {
+			panic(err)
+		}
+	}
+	if o.tracer != nil {
+		if err = orm.Use(xorm.NewTracingPlugin(o.tracer)); err != nil {
+			panic(err)
+		}
+	}
 
 	return xorm.New(orm)
 }
@@ -0,0 +1,27 @@
+package xmysql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlowThreshold(t *testing.T) {
+	type testCase struct {
+		name      string
+		threshold time.Duration
+		expect    time.Duration
+	}
+	testTable := []testCase{
+		{name: "default", threshold: 0, expect: time.Second},
+		{name: "custom", threshold: 200 * time.Millisecond, expect: 200 * time.Millisecond},
+		{name: "disabled", threshold: -1, expect: 0},
+	}
+
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			if got := slowThreshold(Config{SlowThreshold: v.threshold}); got != v.expect {
+				t.Errorf("expect %v, but get %v", v.expect, got)
+			}
+		})
+	}
+}
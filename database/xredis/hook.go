@@ -0,0 +1,132 @@
+package xredis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"skuld/xlogger"
+)
+
+// Recorder 记录每条命令的耗时和结果, 可以实现该接口将数据导出为监控指标
+type Recorder interface {
+	Record(cmd string, d time.Duration, err error)
+}
+
+// CommandStats 单个命令的统计数据
+type CommandStats struct {
+	Calls  int64
+	Errors int64
+	Total  time.Duration
+	Max    time.Duration
+}
+
+// Stats 基于内存的 Recorder 实现
+type Stats struct {
+	mu   sync.Mutex
+	cmds map[string]*CommandStats
+}
+
+func NewStats() *Stats {
+	return &Stats{cmds: make(map[string]*CommandStats)}
+}
+
+func (s *Stats) Record(cmd string, d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cs, ok := s.cmds[cmd]
+	if !ok {
+		cs = &CommandStats{}
+		s.cmds[cmd] = cs
+	}
+	cs.Calls++
+	cs.Total += d
+	if d > cs.Max {
+		cs.Max = d
+	}
+	if err != nil {
+		cs.Errors++
+	}
+}
+
+// Snapshot 返回当前统计数据的拷贝
+func (s *Stats) Snapshot() map[string]CommandStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := make(map[string]CommandStats, len(s.cmds))
+	for k, v := range s.cmds {
+		m[k] = *v
+	}
+	return m
+}
+
+// 慢命令日志中命令内容的最大长度
+const maxSlowLogLen = 512
+
+type startKey struct{}
+
+// instrumentHook 记录命令耗时与错误, 并将慢命令输出到日志
+type instrumentHook struct {
+	logger   xlogger.Logger
+	recorder Recorder
+	slow     time.Duration
+}
+
+func (h instrumentHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+func (h instrumentHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.observe(ctx, cmd.FullName(), cmd.Err(), func() string { return cmd.String() })
+	return nil
+}
+
+func (h instrumentHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+func (h instrumentHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if e := cmd.Err(); e != nil && !errors.Is(e, redis.Nil) {
+			err = e
+			break
+		}
+	}
+	h.observe(ctx, "pipeline", err, func() string {
+		names := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			names = append(names, cmd.FullName())
+		}
+		return strings.Join(names, " ")
+	})
+	return nil
+}
+
+func (h instrumentHook) observe(ctx context.Context, name string, err error, detail func() string) {
+	start, ok := ctx.Value(startKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	d := time.Since(start)
+	if errors.Is(err, redis.Nil) {
+		err = nil
+	}
+
+	if h.recorder != nil {
+		h.recorder.Record(name, d, err)
+	}
+	if h.logger != nil && h.slow > 0 && d >= h.slow {
+		cmd := detail()
+		if len(cmd) > maxSlowLogLen {
+			cmd = cmd[:maxSlowLogLen] + "..."
+		}
+		h.logger.Warn("redis slow command", "cmd", cmd, "duration", d.Milliseconds())
+	}
+}
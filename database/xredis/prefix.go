@@ -0,0 +1,253 @@
+package xredis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// keyPos 返回命令参数中需要添加前缀的下标
+type keyPos func(args []interface{}) []int
+
+// nthKey 命令的第 n 个参数是 key
+func nthKey(n int) keyPos {
+	return func(args []interface{}) []int {
+		if len(args) <= n {
+			return nil
+		}
+		return []int{n}
+	}
+}
+
+// positions 命令的指定参数是 key
+func positions(ns ...int) keyPos {
+	return func(args []interface{}) []int {
+		pos := make([]int, 0, len(ns))
+		for _, n := range ns {
+			if n < len(args) {
+				pos = append(pos, n)
+			}
+		}
+		return pos
+	}
+}
+
+// rangeKeys 从 start 开始每隔 step 个参数是一个 key, 末尾 tail 个参数不是 key
+func rangeKeys(start, step, tail int) keyPos {
+	return func(args []interface{}) []int {
+		pos := make([]int, 0, len(args))
+		for i := start; i < len(args)-tail; i += step {
+			pos = append(pos, i)
+		}
+		return pos
+	}
+}
+
+// numKeys 第 n 个参数是 key 的数量, 随后是对应数量的 key, 第 first 个参数为独立的 key (如 destination)
+func numKeys(first, n int) keyPos {
+	return func(args []interface{}) []int {
+		pos := make([]int, 0)
+		if first > 0 && len(args) > first {
+			pos = append(pos, first)
+		}
+		if len(args) <= n {
+			return pos
+		}
+		cnt, err := strconv.Atoi(argString(args[n]))
+		if err != nil {
+			return pos
+		}
+		for i := n + 1; i <= n+cnt && i < len(args); i++ {
+			pos = append(pos, i)
+		}
+		return pos
+	}
+}
+
+// streamKeys XREAD / XREADGROUP 中 STREAMS 之后前一半参数是 key
+func streamKeys(args []interface{}) []int {
+	for i, arg := range args {
+		if strings.EqualFold(argString(arg), "streams") {
+			rest := len(args) - i - 1
+			pos := make([]int, 0, rest/2)
+			for j := i + 1; j < i+1+rest/2; j++ {
+				pos = append(pos, j)
+			}
+			return pos
+		}
+	}
+	return nil
+}
+
+// subKey 第 1 个参数为 subs 中的子命令时, 第 2 个参数是 key, 如 OBJECT ENCODING key, MEMORY USAGE key
+func subKey(subs ...string) keyPos {
+	return func(args []interface{}) []int {
+		if len(args) <= 2 {
+			return nil
+		}
+		sub := argString(args[1])
+		for _, s := range subs {
+			if strings.EqualFold(sub, s) {
+				return []int{2}
+			}
+		}
+		return nil
+	}
+}
+
+// storeKey 在 pos 的基础上, opts 中的选项 (如 SORT ... STORE dest) 之后的参数也是 key
+func storeKey(pos keyPos, opts ...string) keyPos {
+	return func(args []interface{}) []int {
+		keys := pos(args)
+		for i := len(keys) + 1; i < len(args)-1; i++ {
+			arg := argString(args[i])
+			for _, opt := range opts {
+				if strings.EqualFold(arg, opt) {
+					keys = append(keys, i+1)
+					i++
+					break
+				}
+			}
+		}
+		return keys
+	}
+}
+
+var (
+	keyPositions = map[string]keyPos{}
+	// keyless 不包含 key 的命令, 其他未注册的命令无法添加前缀, 直接返回错误
+	keyless = map[string]struct{}{}
+)
+
+func register(pos keyPos, cmds ...string) {
+	for _, cmd := range cmds {
+		keyPositions[cmd] = pos
+	}
+}
+
+func init() {
+	register(nthKey(1),
+		// generic
+		"dump", "expire", "expireat", "expiretime", "persist", "pexpire", "pexpireat", "pexpiretime",
+		"pttl", "restore", "ttl", "type", "move",
+		// string
+		"append", "decr", "decrby", "get", "getdel", "getex", "getrange", "getset", "incr", "incrby",
+		"incrbyfloat", "psetex", "set", "setex", "setnx", "setrange", "strlen", "setbit", "getbit",
+		"bitcount", "bitpos", "bitfield",
+		// hash
+		"hdel", "hexists", "hget", "hgetall", "hincrby", "hincrbyfloat", "hkeys", "hlen", "hmget",
+		"hmset", "hset", "hsetnx", "hstrlen", "hvals", "hscan", "hrandfield",
+		// list
+		"lindex", "linsert", "llen", "lpop", "lpos", "lpush", "lpushx", "lrange", "lrem", "lset",
+		"ltrim", "rpop", "rpush", "rpushx",
+		// set
+		"sadd", "scard", "sismember", "smismember", "smembers", "spop", "srandmember", "srem", "sscan",
+		// sorted set
+		"zadd", "zcard", "zcount", "zincrby", "zlexcount", "zmscore", "zpopmax", "zpopmin",
+		"zrandmember", "zrange", "zrangebylex", "zrangebyscore", "zrank", "zrem", "zremrangebylex",
+		"zremrangebyrank", "zremrangebyscore", "zrevrange", "zrevrangebylex", "zrevrangebyscore",
+		"zrevrank", "zscan", "zscore",
+		// hyperloglog / geo
+		"pfadd", "geoadd", "geodist", "geohash", "geopos", "georadius_ro", "georadiusbymember_ro", "geosearch",
+		// stream
+		"xack", "xadd", "xautoclaim", "xclaim", "xdel", "xlen", "xpending", "xrange", "xrevrange", "xtrim",
+	)
+	register(storeKey(nthKey(1), "store"), "sort", "sort_ro")
+	register(storeKey(nthKey(1), "store", "storedist"), "georadius", "georadiusbymember")
+	register(subKey("create", "setid", "destroy", "createconsumer", "delconsumer"), "xgroup")
+	register(subKey("stream", "groups", "consumers"), "xinfo")
+	register(subKey("encoding", "freq", "idletime", "refcount"), "object")
+	register(subKey("usage"), "memory")
+	register(rangeKeys(1, 1, 0),
+		"del", "exists", "unlink", "touch", "mget", "watch", "sdiff", "sinter", "sunion", "sdiffstore",
+		"sinterstore", "sunionstore", "pfcount", "pfmerge")
+	register(rangeKeys(1, 2, 0), "mset", "msetnx")
+	register(rangeKeys(1, 1, 1), "blpop", "brpop", "bzpopmin", "bzpopmax")
+	register(rangeKeys(2, 1, 0), "bitop")
+	register(positions(1, 2), "rename", "renamenx", "rpoplpush", "brpoplpush", "lmove", "blmove", "smove",
+		"copy", "zrangestore", "geosearchstore", "lcs")
+	register(numKeys(0, 2), "eval", "evalsha", "eval_ro", "evalsha_ro", "fcall", "fcall_ro", "blmpop", "bzmpop")
+	register(numKeys(1, 2), "zunionstore", "zinterstore", "zdiffstore")
+	register(numKeys(0, 1), "zunion", "zinter", "zdiff", "zintercard", "sintercard", "lmpop", "zmpop")
+	register(streamKeys, "xread", "xreadgroup")
+
+	for _, cmd := range []string{
+		"ping", "echo", "auth", "hello", "select", "quit", "reset", "client", "command", "config", "info",
+		"time", "dbsize", "flushdb", "flushall", "lastsave", "save", "bgsave", "bgrewriteaof", "role",
+		"slowlog", "latency", "debug", "monitor", "swapdb", "acl", "module", "cluster", "readonly",
+		"readwrite", "wait", "script", "function", "multi", "exec", "discard", "unwatch",
+		"publish", "spublish", "subscribe", "psubscribe", "unsubscribe", "punsubscribe", "pubsub",
+		// 以 pattern 匹配或返回 key 的命令不处理
+		"keys", "scan", "randomkey",
+	} {
+		keyless[cmd] = struct{}{}
+	}
+}
+
+func argString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return ""
+	}
+}
+
+// prefixHook 为命令中的 key 添加统一前缀, 用于多个服务共享一个 redis 时隔离 key
+//
+// KEYS / SCAN 等以 pattern 匹配的命令以及返回值中的 key 不会被处理, 未知 key 位置的命令返回错误, 避免访问未加前缀的 key
+type prefixHook struct {
+	prefix string
+}
+
+func (h prefixHook) apply(cmd redis.Cmder) error {
+	name := cmd.Name()
+	pos, ok := keyPositions[name]
+	if !ok {
+		if _, ok := keyless[name]; ok {
+			return nil
+		}
+		return fmt.Errorf("xredis: unknown key positions of command %q with key prefix", name)
+	}
+
+	args := cmd.Args()
+	for _, i := range pos(args) {
+		switch v := args[i].(type) {
+		case string:
+			args[i] = h.prefix + v
+		case []byte:
+			args[i] = append([]byte(h.prefix), v...)
+		}
+	}
+	return nil
+}
+
+func (h prefixHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, h.apply(cmd)
+}
+
+func (h prefixHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h prefixHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for _, cmd := range cmds {
+		if err := h.apply(cmd); err != nil {
+			return ctx, err
+		}
+	}
+	return ctx, nil
+}
+
+func (h prefixHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
@@ -0,0 +1,131 @@
+package xredis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestPrefixHook(t *testing.T) {
+	type testCase struct {
+		name   string
+		args   []interface{}
+		expect []interface{}
+	}
+
+	testTable := []testCase{
+		{
+			name:   "single key",
+			args:   []interface{}{"set", "k", "v"},
+			expect: []interface{}{"set", "app:k", "v"},
+		},
+		{
+			name:   "multi keys",
+			args:   []interface{}{"del", "a", "b"},
+			expect: []interface{}{"del", "app:a", "app:b"},
+		},
+		{
+			name:   "key value pairs",
+			args:   []interface{}{"mset", "a", "1", "b", "2"},
+			expect: []interface{}{"mset", "app:a", "1", "app:b", "2"},
+		},
+		{
+			name:   "blocking with timeout",
+			args:   []interface{}{"blpop", "a", "b", 0},
+			expect: []interface{}{"blpop", "app:a", "app:b", 0},
+		},
+		{
+			name:   "eval keys",
+			args:   []interface{}{"evalsha", "sha", 2, "a", "b", "arg"},
+			expect: []interface{}{"evalsha", "sha", 2, "app:a", "app:b", "arg"},
+		},
+		{
+			name:   "xreadgroup streams",
+			args:   []interface{}{"xreadgroup", "group", "g", "c", "count", 10, "streams", "s1", "s2", ">", ">"},
+			expect: []interface{}{"xreadgroup", "group", "g", "c", "count", 10, "streams", "app:s1", "app:s2", ">", ">"},
+		},
+		{
+			name:   "xgroup subcommand",
+			args:   []interface{}{"xgroup", "create", "s", "g", "0"},
+			expect: []interface{}{"xgroup", "create", "app:s", "g", "0"},
+		},
+		{
+			name:   "bitop destination and sources",
+			args:   []interface{}{"bitop", "and", "dest", "a", "b"},
+			expect: []interface{}{"bitop", "and", "app:dest", "app:a", "app:b"},
+		},
+		{
+			name:   "sort store",
+			args:   []interface{}{"sort", "k", "limit", 0, 10, "store", "dest"},
+			expect: []interface{}{"sort", "app:k", "limit", 0, 10, "store", "app:dest"},
+		},
+		{
+			name:   "georadius storedist",
+			args:   []interface{}{"georadius", "k", 1.0, 2.0, 3, "km", "storedist", "dest"},
+			expect: []interface{}{"georadius", "app:k", 1.0, 2.0, 3, "km", "storedist", "app:dest"},
+		},
+		{
+			name:   "lmpop numkeys",
+			args:   []interface{}{"lmpop", 2, "a", "b", "left", "count", 1},
+			expect: []interface{}{"lmpop", 2, "app:a", "app:b", "left", "count", 1},
+		},
+		{
+			name:   "bzmpop numkeys",
+			args:   []interface{}{"bzmpop", 0, 1, "a", "min"},
+			expect: []interface{}{"bzmpop", 0, 1, "app:a", "min"},
+		},
+		{
+			name:   "sintercard numkeys",
+			args:   []interface{}{"sintercard", 2, "a", "b", "limit", 1},
+			expect: []interface{}{"sintercard", 2, "app:a", "app:b", "limit", 1},
+		},
+		{
+			name:   "object subcommand",
+			args:   []interface{}{"object", "encoding", "k"},
+			expect: []interface{}{"object", "encoding", "app:k"},
+		},
+		{
+			name:   "memory usage",
+			args:   []interface{}{"memory", "usage", "k", "samples", 0},
+			expect: []interface{}{"memory", "usage", "app:k", "samples", 0},
+		},
+		{
+			name:   "memory stats",
+			args:   []interface{}{"memory", "stats"},
+			expect: []interface{}{"memory", "stats"},
+		},
+		{
+			name:   "no key",
+			args:   []interface{}{"ping"},
+			expect: []interface{}{"ping"},
+		},
+	}
+
+	h := prefixHook{prefix: "app:"}
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			cmd := redis.NewCmd(context.Background(), v.args...)
+			if _, err := h.BeforeProcess(context.Background(), cmd); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cmd.Args(), v.expect) {
+				t.Fatalf("expect %v, but get %v", v.expect, cmd.Args())
+			}
+		})
+	}
+}
+
+func TestPrefixHookUnknownCommand(t *testing.T) {
+	h := prefixHook{prefix: "app:"}
+	cmd := redis.NewCmd(context.Background(), "newcmd", "k")
+	if _, err := h.BeforeProcess(context.Background(), cmd); err == nil {
+		t.Fatal("unknown command should return an error")
+	}
+
+	cmds := []redis.Cmder{redis.NewCmd(context.Background(), "get", "k"), cmd}
+	if _, err := h.BeforeProcessPipeline(context.Background(), cmds); err == nil {
+		t.Fatal("pipeline with unknown command should return an error")
+	}
+}
@@ -0,0 +1,297 @@
+package xdelay
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"skuld/encoding"
+)
+
+// ErrDuplicate 相同 ID 的任务已存在
+var ErrDuplicate = errors.New("xdelay: job already exists")
+
+// ErrLeaseLost 任务已超过可见性超时被放回队列或被其他 worker 认领, 当前 worker 不能再确认或重试
+var ErrLeaseLost = errors.New("xdelay: job lease lost")
+
+var (
+	// enqueueScript 仅当任务 ID 不存在时写入任务
+	// KEYS: jobs, ready  ARGV: id, payload, runAt
+	enqueueScript = redis.NewScript(`
+if redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+return 1
+`)
+
+	// claimScript 将可见性超时的任务放回 ready 并收回租约, 再原子认领到期任务, 为认领的任务记录租约 token
+	// KEYS: ready, running, jobs, attempts, leases  ARGV: now, deadline, limit, token
+	claimScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+for _, id in ipairs(expired) do
+	redis.call('ZREM', KEYS[2], id)
+	redis.call('HDEL', KEYS[5], id)
+	redis.call('ZADD', KEYS[1], ARGV[1], id)
+end
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+local res = {}
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	local payload = redis.call('HGET', KEYS[3], id)
+	if payload then
+		redis.call('ZADD', KEYS[2], ARGV[2], id)
+		redis.call('HSET', KEYS[5], id, ARGV[4])
+		local n = redis.call('HINCRBY', KEYS[4], id, 1)
+		table.insert(res, id)
+		table.insert(res, payload)
+		table.insert(res, n)
+	end
+end
+return res
+`)
+
+	// ackScript 仅当任务仍由当前租约持有时删除任务
+	// KEYS: running, jobs, attempts, leases  ARGV: id, token
+	ackScript = redis.NewScript(`
+if redis.call('HGET', KEYS[4], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('HDEL', KEYS[4], ARGV[1])
+return 1
+`)
+
+	// moveScript 仅当任务仍由当前租约持有时将任务从 running 移到 KEYS[2], 用于重试 (ready) 和死信 (dead)
+	// KEYS: running, target, leases  ARGV: id, token, score
+	moveScript = redis.NewScript(`
+if redis.call('HGET', KEYS[3], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+return 1
+`)
+)
+
+type Option func(*options)
+
+type options struct {
+	visibility  time.Duration
+	maxAttempts int64
+	codec       string
+}
+
+// WithVisibilityTimeout 任务被认领后超过该时间未确认, 会被重新投递
+func WithVisibilityTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.visibility = d
+	}
+}
+
+// WithMaxAttempts 任务最多执行次数, 超过后移入死信集合
+func WithMaxAttempts(n int64) Option {
+	return func(o *options) {
+		o.maxAttempts = n
+	}
+}
+
+// WithCodec 设置任务内容的编码, 默认 json
+func WithCodec(name string) Option {
+	return func(o *options) {
+		o.codec = name
+	}
+}
+
+// Job 被认领的一个任务
+type Job struct {
+	ID       string
+	Payload  []byte
+	Attempts int64
+	// Deadline 可见性超时时间, 之后任务会被重新投递
+	Deadline time.Time
+
+	codec encoding.Codec
+	lease string
+}
+
+// Decode 解码任务内容
+func (j *Job) Decode(v interface{}) error {
+	return j.codec.Unmarshal(j.Payload, v)
+}
+
+// Queue 基于 sorted set 的延迟队列
+type Queue struct {
+	client *redis.Client
+	name   string
+	codec  encoding.Codec
+	opts   options
+
+	ready    string
+	running  string
+	dead     string
+	jobs     string
+	attempts string
+	leases   string
+}
+
+func New(client *redis.Client, name string, opts ...Option) *Queue {
+	options := options{
+		visibility:  30 * time.Second,
+		maxAttempts: 10,
+		codec:       "json",
+	}
+	for _, option := range opts {
+		option(&options)
+	}
+
+	codec := encoding.GetCodec(options.codec)
+	if codec == nil {
+		panic(fmt.Sprintf("xdelay: codec %s is not registered", options.codec))
+	}
+
+	// 使用 hash tag 保证集群模式下所有 key 落在同一个 slot
+	prefix := "xdelay:{" + name + "}:"
+	return &Queue{
+		client:   client,
+		name:     name,
+		codec:    codec,
+		opts:     options,
+		ready:    prefix + "ready",
+		running:  prefix + "running",
+		dead:     prefix + "dead",
+		jobs:     prefix + "jobs",
+		attempts: prefix + "attempts",
+		leases:   prefix + "leases",
+	}
+}
+
+func (q *Queue) Name() string {
+	return q.name
+}
+
+// Enqueue 添加一个在 runAt 执行的任务, 相同 id 的任务已存在时返回 ErrDuplicate
+func (q *Queue) Enqueue(ctx context.Context, id string, v interface{}, runAt time.Time) error {
+	data, err := q.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	n, err := enqueueScript.Run(ctx, q.client, []string{q.jobs, q.ready}, id, data, runAt.UnixMilli()).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrDuplicate
+	}
+	return nil
+}
+
+// EnqueueIn 添加一个在 delay 之后执行的任务
+func (q *Queue) EnqueueIn(ctx context.Context, id string, v interface{}, delay time.Duration) error {
+	return q.Enqueue(ctx, id, v, time.Now().Add(delay))
+}
+
+// Remove 删除任务, 例如订单已支付后取消超时关单任务
+func (q *Queue) Remove(ctx context.Context, id string) error {
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, q.ready, id)
+		pipe.ZRem(ctx, q.running, id)
+		pipe.ZRem(ctx, q.dead, id)
+		pipe.HDel(ctx, q.jobs, id)
+		pipe.HDel(ctx, q.attempts, id)
+		pipe.HDel(ctx, q.leases, id)
+		return nil
+	})
+	return err
+}
+
+// Claim 原子认领最多 limit 个到期任务, 认领后任务在可见性超时时间内不会被其他 worker 获取
+func (q *Queue) Claim(ctx context.Context, limit int) ([]*Job, error) {
+	now := time.Now()
+	deadline := now.Add(q.opts.visibility)
+	lease, err := newLease()
+	if err != nil {
+		return nil, err
+	}
+	rst, err := claimScript.Run(ctx, q.client, []string{q.ready, q.running, q.jobs, q.attempts, q.leases},
+		now.UnixMilli(), deadline.UnixMilli(), limit, lease).Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(rst)/3)
+	for i := 0; i+2 < len(rst); i += 3 {
+		id, _ := rst[i].(string)
+		payload, _ := rst[i+1].(string)
+		attempts, _ := rst[i+2].(int64)
+		jobs = append(jobs, &Job{
+			ID:       id,
+			Payload:  []byte(payload),
+			Attempts: attempts,
+			Deadline: deadline,
+			codec:    q.codec,
+			lease:    lease,
+		})
+	}
+	return jobs, nil
+}
+
+// Ack 确认任务执行成功并删除, 租约已失效时返回 ErrLeaseLost
+func (q *Queue) Ack(ctx context.Context, job *Job) error {
+	n, err := ackScript.Run(ctx, q.client, []string{q.running, q.jobs, q.attempts, q.leases}, job.ID, job.lease).Int()
+	return leaseResult(n, err)
+}
+
+// Retry 将任务重新排期到 runAt, 租约已失效时返回 ErrLeaseLost
+func (q *Queue) Retry(ctx context.Context, job *Job, runAt time.Time) error {
+	n, err := moveScript.Run(ctx, q.client, []string{q.running, q.ready, q.leases}, job.ID, job.lease, runAt.UnixMilli()).Int()
+	return leaseResult(n, err)
+}
+
+// Bury 将任务移入死信集合, 租约已失效时返回 ErrLeaseLost
+func (q *Queue) Bury(ctx context.Context, job *Job) error {
+	n, err := moveScript.Run(ctx, q.client, []string{q.running, q.dead, q.leases}, job.ID, job.lease, time.Now().UnixMilli()).Int()
+	return leaseResult(n, err)
+}
+
+func leaseResult(n int, err error) error {
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// newLease 生成一次认领的租约 token
+func newLease() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Dead 返回死信集合中的任务 ID
+func (q *Queue) Dead(ctx context.Context) ([]string, error) {
+	return q.client.ZRange(ctx, q.dead, 0, -1).Result()
+}
+
+// Len 返回等待执行的任务数量
+func (q *Queue) Len(ctx context.Context) (int64, error) {
+	return q.client.ZCard(ctx, q.ready).Result()
+}
+
+func (q *Queue) exhausted(job *Job) bool {
+	return q.opts.maxAttempts > 0 && job.Attempts > q.opts.maxAttempts
+}
@@ -0,0 +1,99 @@
+package xdelay
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	_ "skuld/encoding/json"
+)
+
+func newTestQueue(t *testing.T, opts ...Option) *Queue {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	return New(client, "orders", opts...)
+}
+
+func TestQueue(t *testing.T) {
+	q := newTestQueue(t)
+	ctx := context.Background()
+
+	if err := q.EnqueueIn(ctx, "1", map[string]int{"id": 1}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.EnqueueIn(ctx, "1", map[string]int{"id": 1}, 0); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("expect ErrDuplicate, but get %v", err)
+	}
+	if err := q.EnqueueIn(ctx, "2", map[string]int{"id": 2}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := q.Claim(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != "1" || jobs[0].Attempts != 1 {
+		t.Fatalf("expect job 1 claimed once, but get %+v", jobs)
+	}
+	var v map[string]int
+	if err := jobs[0].Decode(&v); err != nil || v["id"] != 1 {
+		t.Fatalf("expect payload {id:1}, but get %v %v", v, err)
+	}
+
+	if err := q.Ack(ctx, jobs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Ack(ctx, jobs[0]); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expect ErrLeaseLost for acked job, but get %v", err)
+	}
+	if n, _ := q.Len(ctx); n != 1 {
+		t.Fatalf("expect 1 job left, but get %d", n)
+	}
+}
+
+func TestQueueLease(t *testing.T) {
+	q := newTestQueue(t, WithVisibilityTimeout(20*time.Millisecond))
+	ctx := context.Background()
+
+	if err := q.EnqueueIn(ctx, "1", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := q.Claim(ctx, 1)
+	if err != nil || len(stale) != 1 {
+		t.Fatalf("expect 1 job claimed, but get %v %v", stale, err)
+	}
+
+	// 可见性超时后被重新认领, 之前的租约失效
+	time.Sleep(30 * time.Millisecond)
+	jobs, err := q.Claim(ctx, 1)
+	if err != nil || len(jobs) != 1 || jobs[0].Attempts != 2 {
+		t.Fatalf("expect job reclaimed with attempts 2, but get %+v %v", jobs, err)
+	}
+	if err := q.Ack(ctx, stale[0]); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expect ErrLeaseLost for stale Ack, but get %v", err)
+	}
+	if err := q.Retry(ctx, stale[0], time.Now()); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expect ErrLeaseLost for stale Retry, but get %v", err)
+	}
+	if err := q.Bury(ctx, stale[0]); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expect ErrLeaseLost for stale Bury, but get %v", err)
+	}
+
+	if err := q.Retry(ctx, jobs[0], time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err = q.Claim(ctx, 1)
+	if err != nil || len(jobs) != 1 || jobs[0].Attempts != 3 {
+		t.Fatalf("expect retried job claimed with attempts 3, but get %+v %v", jobs, err)
+	}
+	if err := q.Bury(ctx, jobs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if dead, _ := q.Dead(ctx); len(dead) != 1 || dead[0] != "1" {
+		t.Fatalf("expect job 1 in dead set, but get %v", dead)
+	}
+}
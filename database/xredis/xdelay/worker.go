@@ -0,0 +1,287 @@
+package xdelay
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"skuld/xlogger"
+)
+
+// Handler 执行一个任务, 返回错误时任务按退避策略重试; ctx 在任务的可见性超时时间 (job.Deadline) 取消
+type Handler func(ctx context.Context, job *Job) error
+
+// Backoff 根据已执行次数返回下一次重试的延迟
+type Backoff func(attempts int64) time.Duration
+
+// ExponentialBackoff 指数退避, 从 min 开始每次翻倍, 最大不超过 max
+func ExponentialBackoff(min, max time.Duration) Backoff {
+	return func(attempts int64) time.Duration {
+		d := min
+		for i := int64(1); i < attempts && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+type WorkerOption func(*workerOptions)
+
+type workerOptions struct {
+	concurrency  int
+	pollInterval time.Duration
+	batchSize    int
+	backoff      Backoff
+}
+
+// WithConcurrency 设置并发执行任务的 goroutine 数量
+func WithConcurrency(n int) WorkerOption {
+	return func(o *workerOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithPollInterval 设置没有到期任务时的轮询间隔
+func WithPollInterval(d time.Duration) WorkerOption {
+	return func(o *workerOptions) {
+		o.pollInterval = d
+	}
+}
+
+// WithBatchSize 设置每次认领的最大任务数量, 实际认领数量不超过空闲的 goroutine 数量,
+// 避免认领的任务排队等待期间可见性超时
+func WithBatchSize(n int) WorkerOption {
+	return func(o *workerOptions) {
+		o.batchSize = n
+	}
+}
+
+// WithBackoff 设置失败重试的退避策略
+func WithBackoff(b Backoff) WorkerOption {
+	return func(o *workerOptions) {
+		o.backoff = b
+	}
+}
+
+// Worker 轮询并执行到期任务, 实现了 app.Server
+type Worker struct {
+	queue   *Queue
+	handler Handler
+	logger  xlogger.Logger
+	opts    workerOptions
+
+	jobs chan *Job
+	// idle 空闲的 goroutine 数量, 容量为 concurrency
+	idle    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	work    sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+	done    chan struct{}
+}
+
+func NewWorker(queue *Queue, handler Handler, logger xlogger.Logger, opts ...WorkerOption) *Worker {
+	if handler == nil {
+		panic("handler is nil")
+	}
+	if logger == nil {
+		panic("logger is nil")
+	}
+
+	options := workerOptions{
+		concurrency:  1,
+		pollInterval: time.Second,
+		batchSize:    10,
+		backoff:      ExponentialBackoff(time.Second, 10*time.Minute),
+	}
+	for _, option := range opts {
+		option(&options)
+	}
+	if options.concurrency < 1 {
+		options.concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Worker{
+		queue:   queue,
+		handler: handler,
+		logger:  logger,
+		opts:    options,
+		jobs:    make(chan *Job),
+		idle:    make(chan struct{}, options.concurrency),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	w.release(options.concurrency)
+	return w
+}
+
+// Run 开始轮询任务, 阻塞直到 Close 被调用
+func (w *Worker) Run() error {
+	w.mu.Lock()
+	if w.ctx.Err() != nil || w.running {
+		w.mu.Unlock()
+		return nil
+	}
+	w.running = true
+	w.mu.Unlock()
+	defer close(w.done)
+
+	for i := 0; i < w.opts.concurrency; i++ {
+		w.work.Add(1)
+		go w.worker()
+	}
+
+	w.poll()
+
+	close(w.jobs)
+	w.work.Wait()
+	return nil
+}
+
+// Close 停止认领新任务, 并等待正在执行的任务完成或 ctx 超时
+func (w *Worker) Close(ctx context.Context) error {
+	w.mu.Lock()
+	w.cancel()
+	running := w.running
+	w.mu.Unlock()
+	if !running {
+		return nil
+	}
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Worker) poll() {
+	for w.ctx.Err() == nil {
+		n := w.acquire()
+		if n == 0 {
+			return
+		}
+		jobs, err := w.queue.Claim(w.ctx, n)
+		if err != nil {
+			w.release(n)
+			if w.ctx.Err() == nil {
+				w.logger.Warn("xdelay Claim", "queue", w.queue.Name(), "err", err)
+			}
+			w.sleep(w.opts.pollInterval)
+			continue
+		}
+		w.release(n - len(jobs))
+
+		for _, job := range jobs {
+			if w.queue.exhausted(job) {
+				w.bury(job)
+				w.release(1)
+				continue
+			}
+			select {
+			case w.jobs <- job:
+			case <-w.ctx.Done():
+				// 未分发的任务会在可见性超时后重新投递
+				return
+			}
+		}
+
+		if len(jobs) < n {
+			w.sleep(w.opts.pollInterval)
+		}
+	}
+}
+
+// acquire 等待至少一个空闲的 goroutine, 返回本次可以认领的任务数量, Close 后返回 0
+func (w *Worker) acquire() int {
+	select {
+	case <-w.idle:
+	case <-w.ctx.Done():
+		return 0
+	}
+	n := 1
+	for n < w.opts.batchSize {
+		select {
+		case <-w.idle:
+			n++
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+func (w *Worker) release(n int) {
+	for i := 0; i < n; i++ {
+		w.idle <- struct{}{}
+	}
+}
+
+func (w *Worker) worker() {
+	defer w.work.Done()
+
+	// 使用独立的 context, 保证 Close 期间已执行完的任务仍能被确认
+	ctx := context.Background()
+	for job := range w.jobs {
+		w.process(ctx, job)
+		w.release(1)
+	}
+}
+
+// process 执行任务并根据结果确认, 重试或移入死信集合
+func (w *Worker) process(ctx context.Context, job *Job) {
+	if err := w.handle(job); err != nil {
+		if w.queue.opts.maxAttempts > 0 && job.Attempts >= w.queue.opts.maxAttempts {
+			w.logger.Warn("xdelay handle", "queue", w.queue.Name(), "id", job.ID, "attempts", job.Attempts, "err", err)
+			w.bury(job)
+			return
+		}
+		delay := w.opts.backoff(job.Attempts)
+		w.logger.Warn("xdelay handle", "queue", w.queue.Name(), "id", job.ID, "attempts", job.Attempts, "retry_in", delay.String(), "err", err)
+		if err := w.queue.Retry(ctx, job, time.Now().Add(delay)); err != nil {
+			w.logger.Error("xdelay Retry", "queue", w.queue.Name(), "id", job.ID, "err", err)
+		}
+		return
+	}
+	if err := w.queue.Ack(ctx, job); err != nil {
+		w.logger.Error("xdelay Ack", "queue", w.queue.Name(), "id", job.ID, "err", err)
+	}
+}
+
+// handle 执行任务, handler 的 ctx 在可见性超时时间到达时取消, 之后任务可能已被其他 worker 认领
+func (w *Worker) handle(job *Job) (err error) {
+	ctx, cancel := context.WithDeadline(context.Background(), job.Deadline)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			w.logger.Error("xdelay handler panic", "queue", w.queue.Name(), "id", job.ID, "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+
+	return w.handler(ctx, job)
+}
+
+func (w *Worker) bury(job *Job) {
+	if err := w.queue.Bury(context.Background(), job); err != nil {
+		w.logger.Error("xdelay Bury", "queue", w.queue.Name(), "id", job.ID, "err", err)
+		return
+	}
+	w.logger.Warn("xdelay job moved to dead set", "queue", w.queue.Name(), "id", job.ID, "attempts", job.Attempts)
+}
+
+func (w *Worker) sleep(d time.Duration) {
+	select {
+	case <-w.ctx.Done():
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,134 @@
+package xdelay
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"skuld/xlogger/xloggertest"
+)
+
+func TestWorker(t *testing.T) {
+	q := newTestQueue(t, WithVisibilityTimeout(time.Second), WithMaxAttempts(2))
+	ctx := context.Background()
+
+	type result struct {
+		id       string
+		attempts int64
+		deadline time.Time
+		expect   time.Time
+	}
+	results := make(chan result, 10)
+	w := NewWorker(q, func(ctx context.Context, job *Job) error {
+		d, _ := ctx.Deadline()
+		results <- result{id: job.ID, attempts: job.Attempts, deadline: d, expect: job.Deadline}
+		if job.ID == "fail" {
+			return errors.New("boom")
+		}
+		return nil
+	}, xloggertest.NewT(t), WithPollInterval(10*time.Millisecond), WithBackoff(func(int64) time.Duration { return 0 }))
+	go func() { _ = w.Run() }()
+	defer w.Close(ctx)
+
+	if err := q.EnqueueIn(ctx, "ok", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.EnqueueIn(ctx, "fail", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := map[string]int64{}
+	for attempts["ok"] < 1 || attempts["fail"] < 2 {
+		select {
+		case r := <-results:
+			attempts[r.id] = r.attempts
+			// handler 的 ctx 在可见性超时时间取消
+			if r.deadline.IsZero() || !r.deadline.Equal(r.expect) {
+				t.Errorf("expect ctx deadline %v, but get %v", r.expect, r.deadline)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expect jobs handled, but get %v", attempts)
+		}
+	}
+
+	// 达到最大执行次数后移入死信集合
+	deadline := time.Now().Add(time.Second)
+	for {
+		dead, err := q.Dead(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dead) == 1 && dead[0] == "fail" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expect job fail in dead set, but get %v", dead)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n, _ := q.Len(ctx); n != 0 {
+		t.Errorf("expect no ready jobs, but get %d", n)
+	}
+}
+
+func TestWorkerClaimIdle(t *testing.T) {
+	// 可见性超时小于批量任务串行执行的总耗时
+	q := newTestQueue(t, WithVisibilityTimeout(250*time.Millisecond))
+	ctx := context.Background()
+
+	var (
+		mu      sync.Mutex
+		handled = map[string]int{}
+		expired []string
+	)
+	w := NewWorker(q, func(ctx context.Context, job *Job) error {
+		if ctx.Err() != nil {
+			mu.Lock()
+			expired = append(expired, job.ID)
+			mu.Unlock()
+		}
+		time.Sleep(100 * time.Millisecond)
+		mu.Lock()
+		handled[job.ID]++
+		mu.Unlock()
+		return nil
+	}, xloggertest.NewT(t), WithConcurrency(2), WithBatchSize(10), WithPollInterval(10*time.Millisecond))
+
+	ids := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	for _, id := range ids {
+		if err := q.EnqueueIn(ctx, id, 1, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	go func() { _ = w.Run() }()
+	defer w.Close(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(handled)
+		mu.Unlock()
+		if n == len(ids) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expect all jobs handled, but get %v", handled)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// 等待可能的重复投递
+	time.Sleep(300 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for id, n := range handled {
+		if n != 1 {
+			t.Errorf("expect job %s handled once, but get %d", id, n)
+		}
+	}
+	if len(expired) > 0 {
+		t.Errorf("expect handler ctx alive when job starts, but expired for %v", expired)
+	}
+}
@@ -5,9 +5,37 @@ import (
 	"runtime"
 
 	"github.com/go-redis/redis/v8"
+
+	"skuld/xlogger"
 )
 
-func New(c Config) *redis.Client {
+type Option func(*options)
+
+type options struct {
+	logger   xlogger.Logger
+	recorder Recorder
+}
+
+// WithLogger 慢命令 (耗时超过 Config.SlowThreshold) 通过 logger 输出
+func WithLogger(logger xlogger.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithRecorder 记录每条命令的耗时和错误
+func WithRecorder(recorder Recorder) Option {
+	return func(o *options) {
+		o.recorder = recorder
+	}
+}
+
+func New(c Config, opts ...Option) *redis.Client {
+	options := options{}
+	for _, option := range opts {
+		option(&options)
+	}
+
 	client := redis.NewClient(&redis.Options{
 		Addr:         c.Addr,
 		Password:     c.Password,
@@ -20,6 +48,17 @@ func New(c Config) *redis.Client {
 		IdleTimeout:  c.IdleTimeout,
 	})
 
+	if options.logger != nil || options.recorder != nil {
+		client.AddHook(instrumentHook{
+			logger:   options.logger,
+			recorder: options.recorder,
+			slow:     c.SlowThreshold,
+		})
+	}
+	if c.KeyPrefix != "" {
+		client.AddHook(prefixHook{prefix: c.KeyPrefix})
+	}
+
 	if err := client.Ping(context.Background()).Err(); err != nil {
 		panic(err)
 	}
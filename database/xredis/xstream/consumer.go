@@ -0,0 +1,416 @@
+package xstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"skuld/xlogger"
+)
+
+// Handler 处理一条消息, 返回 nil 时消息会被 XACK, 否则留在 pending 列表中等待重新投递;
+// ctx 在 Close 等待超时后取消, 长时间的处理需要检查 ctx.Done()
+type Handler func(ctx context.Context, msg *Message) error
+
+type Option func(*options)
+
+type options struct {
+	consumer      string
+	concurrency   int
+	batchSize     int64
+	block         time.Duration
+	minIdle       time.Duration
+	claimInterval time.Duration
+	maxAttempts   int64
+	deadLetter    string
+	codec         string
+}
+
+// WithConsumerName 设置消费者名称, 默认 hostname-pid
+func WithConsumerName(name string) Option {
+	return func(o *options) {
+		o.consumer = name
+	}
+}
+
+// WithConcurrency 设置并发处理消息的 goroutine 数量
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		o.concurrency = n
+	}
+}
+
+// WithBatchSize 设置每次 XREADGROUP / XAUTOCLAIM 读取的消息数量
+func WithBatchSize(n int64) Option {
+	return func(o *options) {
+		o.batchSize = n
+	}
+}
+
+// WithBlock 设置 XREADGROUP 阻塞时间, 同时决定了 Close 时读取循环的最长退出时间
+func WithBlock(d time.Duration) Option {
+	return func(o *options) {
+		o.block = d
+	}
+}
+
+// WithReclaim 设置 pending 消息空闲多久后被重新认领, 以及认领的检查间隔
+func WithReclaim(minIdle, interval time.Duration) Option {
+	return func(o *options) {
+		o.minIdle = minIdle
+		o.claimInterval = interval
+	}
+}
+
+// WithDeadLetter 消息投递次数超过 maxAttempts 后移入 stream 死信队列
+func WithDeadLetter(stream string, maxAttempts int64) Option {
+	return func(o *options) {
+		o.deadLetter = stream
+		o.maxAttempts = maxAttempts
+	}
+}
+
+// WithCodec 设置消息未携带 codec 字段时使用的解码器
+func WithCodec(name string) Option {
+	return func(o *options) {
+		o.codec = name
+	}
+}
+
+// Consumer 基于消费者组的 stream 消费者, 实现了 app.Server
+type Consumer struct {
+	client  *redis.Client
+	stream  string
+	group   string
+	handler Handler
+	logger  xlogger.Logger
+	opts    options
+
+	msgs   chan *Message
+	ctx    context.Context
+	cancel context.CancelFunc
+	// handleCtx 传给 handler, Close 等待超时后取消
+	handleCtx    context.Context
+	handleCancel context.CancelFunc
+	fetch        sync.WaitGroup
+	work         sync.WaitGroup
+	mu           sync.Mutex
+	running      bool
+	done         chan struct{}
+}
+
+func NewConsumer(client *redis.Client, stream, group string, handler Handler, logger xlogger.Logger, opts ...Option) *Consumer {
+	if handler == nil {
+		panic("handler is nil")
+	}
+	if logger == nil {
+		panic("logger is nil")
+	}
+
+	hostname, _ := os.Hostname()
+	options := options{
+		consumer:      fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		concurrency:   1,
+		batchSize:     10,
+		block:         2 * time.Second,
+		minIdle:       time.Minute,
+		claimInterval: 30 * time.Second,
+		maxAttempts:   0,
+		codec:         "json",
+	}
+	for _, option := range opts {
+		option(&options)
+	}
+	if options.concurrency < 1 {
+		options.concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handleCtx, handleCancel := context.WithCancel(context.Background())
+	return &Consumer{
+		client:       client,
+		stream:       stream,
+		group:        group,
+		handler:      handler,
+		logger:       logger,
+		opts:         options,
+		msgs:         make(chan *Message),
+		ctx:          ctx,
+		cancel:       cancel,
+		handleCtx:    handleCtx,
+		handleCancel: handleCancel,
+		done:         make(chan struct{}),
+	}
+}
+
+// Run 创建消费者组并开始消费, 阻塞直到 Close 被调用
+func (c *Consumer) Run() error {
+	c.mu.Lock()
+	if c.ctx.Err() != nil || c.running {
+		c.mu.Unlock()
+		return nil
+	}
+	c.running = true
+	c.mu.Unlock()
+	defer close(c.done)
+	defer c.handleCancel()
+
+	err := c.client.XGroupCreateMkStream(c.ctx, c.stream, c.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	for i := 0; i < c.opts.concurrency; i++ {
+		c.work.Add(1)
+		go c.worker()
+	}
+
+	c.fetch.Add(2)
+	go c.reclaimLoop()
+	go c.readLoop()
+
+	c.fetch.Wait()
+	close(c.msgs)
+	c.work.Wait()
+	return nil
+}
+
+// Close 停止读取新消息, 并等待正在处理的消息完成; ctx 超时后取消传给 handler 的 ctx 并返回
+func (c *Consumer) Close(ctx context.Context) error {
+	c.mu.Lock()
+	c.cancel()
+	running := c.running
+	c.mu.Unlock()
+	if !running {
+		c.handleCancel()
+		return nil
+	}
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		c.handleCancel()
+		return ctx.Err()
+	}
+}
+
+func (c *Consumer) readLoop() {
+	defer c.fetch.Done()
+
+	for c.ctx.Err() == nil {
+		streams, err := c.client.XReadGroup(c.ctx, &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.opts.consumer,
+			Streams:  []string{c.stream, ">"},
+			Count:    c.opts.batchSize,
+			Block:    c.opts.block,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || c.ctx.Err() != nil {
+				continue
+			}
+			c.logger.Warn("xstream XReadGroup", "stream", c.stream, "group", c.group, "err", err)
+			c.sleep(time.Second)
+			continue
+		}
+
+		for _, s := range streams {
+			for _, m := range s.Messages {
+				if !c.dispatch(newMessage(c.stream, 1, c.opts.codec, m)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *Consumer) reclaimLoop() {
+	defer c.fetch.Done()
+
+	if c.opts.claimInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.opts.claimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.reclaim(); err != nil && c.ctx.Err() == nil {
+				c.logger.Warn("xstream reclaim", "stream", c.stream, "group", c.group, "err", err)
+			}
+		}
+	}
+}
+
+// reclaim 通过 XAUTOCLAIM 认领空闲超过 minIdle 的消息, 投递次数超限的消息移入死信队列
+func (c *Consumer) reclaim() error {
+	start := "0-0"
+	for c.ctx.Err() == nil {
+		next, msgs, err := c.autoClaim(start)
+		if err != nil {
+			return err
+		}
+		if len(msgs) > 0 {
+			attempts, err := c.deliveries(msgs)
+			if err != nil {
+				return err
+			}
+			for _, m := range msgs {
+				n := attempts[m.ID]
+				if c.opts.deadLetter != "" && c.opts.maxAttempts > 0 && n > c.opts.maxAttempts {
+					if err := c.deadLetter(m, n); err != nil {
+						c.logger.Error("xstream deadLetter", "stream", c.stream, "id", m.ID, "err", err)
+					}
+					continue
+				}
+				if !c.dispatch(newMessage(c.stream, n, c.opts.codec, m)) {
+					return nil
+				}
+			}
+		}
+		if next == "" || next == "0-0" {
+			return nil
+		}
+		start = next
+	}
+	return nil
+}
+
+// autoClaim 直接发送 XAUTOCLAIM 并自行解析, 以兼容 redis 7 多返回的已删除 ID 列表
+func (c *Consumer) autoClaim(start string) (string, []redis.XMessage, error) {
+	rst, err := c.client.Do(c.ctx, "xautoclaim", c.stream, c.group, c.opts.consumer,
+		c.opts.minIdle.Milliseconds(), start, "count", c.opts.batchSize).Slice()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(rst) < 2 {
+		return "", nil, fmt.Errorf("xstream: unexpected xautoclaim reply length %d", len(rst))
+	}
+
+	next, _ := rst[0].(string)
+	entries, _ := rst[1].([]interface{})
+	msgs := make([]redis.XMessage, 0, len(entries))
+	for _, e := range entries {
+		entry, ok := e.([]interface{})
+		if !ok || len(entry) != 2 {
+			continue
+		}
+		id, _ := entry[0].(string)
+		fields, _ := entry[1].([]interface{})
+		values := make(map[string]interface{}, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			if k, ok := fields[i].(string); ok {
+				values[k] = fields[i+1]
+			}
+		}
+		msgs = append(msgs, redis.XMessage{ID: id, Values: values})
+	}
+	return next, msgs, nil
+}
+
+// deliveries 查询消息的投递次数
+func (c *Consumer) deliveries(msgs []redis.XMessage) (map[string]int64, error) {
+	cmds := make([]*redis.XPendingExtCmd, 0, len(msgs))
+	_, err := c.client.Pipelined(c.ctx, func(pipe redis.Pipeliner) error {
+		for _, m := range msgs {
+			cmds = append(cmds, pipe.XPendingExt(c.ctx, &redis.XPendingExtArgs{
+				Stream: c.stream,
+				Group:  c.group,
+				Start:  m.ID,
+				End:    m.ID,
+				Count:  1,
+			}))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := make(map[string]int64, len(msgs))
+	for _, cmd := range cmds {
+		for _, p := range cmd.Val() {
+			attempts[p.ID] = p.RetryCount
+		}
+	}
+	return attempts, nil
+}
+
+func (c *Consumer) deadLetter(m redis.XMessage, attempts int64) error {
+	values := make(map[string]interface{}, len(m.Values)+4)
+	for k, v := range m.Values {
+		values[k] = v
+	}
+	values["dead_stream"] = c.stream
+	values["dead_group"] = c.group
+	values["dead_id"] = m.ID
+	values["dead_attempts"] = strconv.FormatInt(attempts, 10)
+
+	_, err := c.client.TxPipelined(c.ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(c.ctx, &redis.XAddArgs{Stream: c.opts.deadLetter, Values: values})
+		pipe.XAck(c.ctx, c.stream, c.group, m.ID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	c.logger.Warn("xstream message moved to dead letter", "stream", c.stream, "id", m.ID,
+		"dead_letter", c.opts.deadLetter, "attempts", attempts)
+	return nil
+}
+
+func (c *Consumer) dispatch(msg *Message) bool {
+	select {
+	case c.msgs <- msg:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+func (c *Consumer) worker() {
+	defer c.work.Done()
+
+	for msg := range c.msgs {
+		if err := c.handle(msg); err != nil {
+			c.logger.Warn("xstream handle", "stream", c.stream, "id", msg.ID, "attempts", msg.Attempts, "err", err)
+			continue
+		}
+		// 使用独立的 context, 保证 Close 期间已处理完的消息仍能被确认
+		if err := c.client.XAck(context.Background(), c.stream, c.group, msg.ID).Err(); err != nil {
+			c.logger.Error("xstream XAck", "stream", c.stream, "id", msg.ID, "err", err)
+		}
+	}
+}
+
+func (c *Consumer) handle(msg *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			c.logger.Error("xstream handler panic", "stream", c.stream, "id", msg.ID, "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+
+	return c.handler(c.handleCtx, msg)
+}
+
+func (c *Consumer) sleep(d time.Duration) {
+	select {
+	case <-c.ctx.Done():
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,154 @@
+package xstream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	_ "skuld/encoding/json"
+	"skuld/xlogger/xloggertest"
+)
+
+type order struct {
+	ID int `json:"id"`
+}
+
+func TestConsumer(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	received := make(chan *Message, 10)
+	failed := false
+	consumer := NewConsumer(client, "orders", "g", func(ctx context.Context, msg *Message) error {
+		var o order
+		if err := msg.Decode(&o); err != nil {
+			return err
+		}
+		// 第一次处理 id 为 2 的消息失败, 之后被 reclaim 重新投递
+		if o.ID == 2 && !failed {
+			failed = true
+			return errors.New("boom")
+		}
+		received <- msg
+		return nil
+	}, xloggertest.NewT(t), WithBlock(10*time.Millisecond), WithReclaim(10*time.Millisecond, 20*time.Millisecond))
+	go func() { _ = consumer.Run() }()
+	defer consumer.Close(ctx)
+
+	producer := NewProducer(client, "orders")
+	for i := 1; i <= 2; i++ {
+		if _, err := producer.Send(ctx, order{ID: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	attempts := map[int]int64{}
+	for len(attempts) < 2 {
+		select {
+		case msg := <-received:
+			var o order
+			_ = msg.Decode(&o)
+			attempts[o.ID] = msg.Attempts
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expect 2 messages, but get %v", attempts)
+		}
+	}
+	if attempts[1] != 1 || attempts[2] != 2 {
+		t.Errorf("expect attempts {1:1 2:2}, but get %v", attempts)
+	}
+
+	// 处理成功的消息被 XACK
+	deadline := time.Now().Add(time.Second)
+	for {
+		pending, err := client.XPending(ctx, "orders", "g").Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pending.Count == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expect no pending messages, but get %d", pending.Count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConsumerDeadLetter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	logger := xloggertest.NewT(t)
+	consumer := NewConsumer(client, "orders", "g", func(ctx context.Context, msg *Message) error {
+		return errors.New("boom")
+	}, logger, WithBlock(10*time.Millisecond), WithReclaim(10*time.Millisecond, 20*time.Millisecond),
+		WithDeadLetter("orders:dead", 2))
+	go func() { _ = consumer.Run() }()
+	defer consumer.Close(ctx)
+
+	id, err := NewProducer(client, "orders").Send(ctx, order{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		msgs, err := client.XRange(ctx, "orders:dead", "-", "+").Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msgs) == 1 {
+			if msgs[0].Values["dead_id"] != id || msgs[0].Values["dead_attempts"] != "3" {
+				t.Errorf("unexpected dead letter %v", msgs[0].Values)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expect message moved to dead letter")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConsumerClose(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	consumer := NewConsumer(client, "orders", "g", func(ctx context.Context, msg *Message) error {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	}, xloggertest.NewT(t), WithBlock(10*time.Millisecond))
+	go func() { _ = consumer.Run() }()
+
+	if _, err := NewProducer(client, "orders").Send(ctx, order{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expect handler started")
+	}
+
+	// Close 等待超时后取消 handler 的 ctx
+	closeCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := consumer.Close(closeCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expect context.DeadlineExceeded, but get %v", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("expect handler ctx canceled after Close")
+	}
+}
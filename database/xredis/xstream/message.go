@@ -0,0 +1,61 @@
+package xstream
+
+import (
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+
+	"skuld/encoding"
+)
+
+const (
+	// FieldPayload 消息体字段
+	FieldPayload = "payload"
+	// FieldCodec 消息体编码字段, 值为 encoding 中注册的 codec 名称
+	FieldCodec = "codec"
+)
+
+// Message 从 stream 中读取到的一条消息
+type Message struct {
+	ID       string
+	Stream   string
+	Values   map[string]interface{}
+	Attempts int64
+
+	codec string
+}
+
+func newMessage(stream string, attempts int64, codec string, m redis.XMessage) *Message {
+	return &Message{
+		ID:       m.ID,
+		Stream:   stream,
+		Values:   m.Values,
+		Attempts: attempts,
+		codec:    codec,
+	}
+}
+
+// Payload 返回消息体原始数据
+func (m *Message) Payload() []byte {
+	switch v := m.Values[FieldPayload].(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	default:
+		return nil
+	}
+}
+
+// Decode 使用消息携带的 codec (缺省为消费者配置的 codec) 解码消息体
+func (m *Message) Decode(v interface{}) error {
+	name := m.codec
+	if s, ok := m.Values[FieldCodec].(string); ok && s != "" {
+		name = s
+	}
+	codec := encoding.GetCodec(name)
+	if codec == nil {
+		return fmt.Errorf("xstream: codec %s is not registered", name)
+	}
+	return codec.Unmarshal(m.Payload(), v)
+}
@@ -0,0 +1,75 @@
+package xstream
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+
+	"skuld/encoding"
+)
+
+type ProducerOption func(*producerOptions)
+
+type producerOptions struct {
+	codec  string
+	maxLen int64
+}
+
+// WithProducerCodec 设置消息体编码, 默认 json
+func WithProducerCodec(name string) ProducerOption {
+	return func(o *producerOptions) {
+		o.codec = name
+	}
+}
+
+// WithMaxLen 近似裁剪 stream 长度, 0 表示不裁剪
+func WithMaxLen(maxLen int64) ProducerOption {
+	return func(o *producerOptions) {
+		o.maxLen = maxLen
+	}
+}
+
+// Producer 向 stream 写入编码后的消息
+type Producer struct {
+	client *redis.Client
+	stream string
+	codec  encoding.Codec
+	opts   producerOptions
+}
+
+func NewProducer(client *redis.Client, stream string, opts ...ProducerOption) *Producer {
+	options := producerOptions{
+		codec: "json",
+	}
+	for _, option := range opts {
+		option(&options)
+	}
+
+	codec := encoding.GetCodec(options.codec)
+	if codec == nil {
+		panic(fmt.Sprintf("xstream: codec %s is not registered", options.codec))
+	}
+
+	return &Producer{
+		client: client,
+		stream: stream,
+		codec:  codec,
+		opts:   options,
+	}
+}
+
+// Send 编码 v 并写入 stream, 返回消息 ID
+func (p *Producer) Send(ctx context.Context, v interface{}) (string, error) {
+	data, err := p.codec.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: p.opts.maxLen,
+		Approx: p.opts.maxLen > 0,
+		Values: []interface{}{FieldPayload, data, FieldCodec, p.codec.Name()},
+	}).Result()
+}
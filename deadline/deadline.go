@@ -0,0 +1,40 @@
+package deadline
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Header 传递请求剩余处理时间 (毫秒) 的 http 请求头, 使用相对时间避免上下游时钟不一致
+const Header = "X-Request-Timeout"
+
+// maxMillis time.Duration 能表示的最大毫秒数
+const maxMillis = math.MaxInt64 / int64(time.Millisecond)
+
+// Parse 解析请求头中的剩余时间, 不是非负整数或超出 time.Duration 的范围时返回 false
+func Parse(v string) (time.Duration, bool) {
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms < 0 || ms > maxMillis {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// Format 将剩余时间转换为请求头的值, 不足 1 毫秒时为 0
+func Format(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
+
+// Remaining 返回 ctx 的剩余时间, ctx 没有截止时间时返回 false
+func Remaining(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(d), true
+}
@@ -0,0 +1,82 @@
+package deadline
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	type testCase struct {
+		name   string
+		value  string
+		expect time.Duration
+		ok     bool
+	}
+
+	testTable := []testCase{
+		{name: "milliseconds", value: "1500", expect: 1500 * time.Millisecond, ok: true},
+		{name: "zero", value: "0", expect: 0, ok: true},
+		{name: "negative", value: "-1", ok: false},
+		{name: "not integer", value: "1.5", ok: false},
+		{name: "empty", value: "", ok: false},
+		{name: "max", value: strconv.FormatInt(maxMillis, 10), expect: time.Duration(maxMillis) * time.Millisecond, ok: true},
+		// 乘以 time.Millisecond 后溢出为负数
+		{name: "overflow", value: strconv.FormatInt(maxMillis+1, 10), ok: false},
+		{name: "out of int64", value: "99999999999999999999", ok: false},
+	}
+
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			d, ok := Parse(v.value)
+			if ok != v.ok || d != v.expect {
+				t.Fatalf("expect %v %v, but get %v %v", v.expect, v.ok, d, ok)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	type testCase struct {
+		name   string
+		d      time.Duration
+		expect string
+	}
+
+	testTable := []testCase{
+		{name: "milliseconds", d: 1500 * time.Millisecond, expect: "1500"},
+		{name: "under one millisecond", d: 999 * time.Microsecond, expect: "0"},
+		{name: "negative", d: -time.Second, expect: "0"},
+	}
+
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			if s := Format(v.d); s != v.expect {
+				t.Fatalf("expect %q, but get %q", v.expect, s)
+			}
+			if d, ok := Parse(Format(v.d)); !ok || d > v.d && v.d >= 0 {
+				t.Fatalf("round trip of %v should not exceed it, but get %v", v.d, d)
+			}
+		})
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	if _, ok := Remaining(context.Background()); ok {
+		t.Fatal("context without deadline should return false")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	d, ok := Remaining(ctx)
+	if !ok || d <= 0 || d > time.Second {
+		t.Fatalf("expect remaining in (0, 1s], but get %v %v", d, ok)
+	}
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if d, ok := Remaining(ctx); !ok || d >= 0 {
+		t.Fatalf("expect negative remaining for expired context, but get %v %v", d, ok)
+	}
+}
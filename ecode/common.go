@@ -0,0 +1,18 @@
+package ecode
+
+// 框架内置错误码, 使用保留的 900000 ~ 900999 段, 后三位为 xgin 返回的 http 状态码;
+// 业务的 100xxx 等错误码不会与其冲突
+var (
+	ServerPanic = New(900500, "服务器异常", "请稍后重试")
+	Timeout     = New(900504, "请求超时", "请稍后重试")
+
+	InvalidParams        = New(900400, "参数错误", "请检查请求参数")
+	UnsupportedMediaType = New(900415, "不支持的 Content-Type")
+	RequestTooLarge      = New(900413, "请求体过大")
+
+	Unauthorized = New(900401, "未登录或登录已过期", "请重新登录")
+	Forbidden    = New(900403, "没有权限")
+
+	IdempotencyInProgress = New(900409, "请求正在处理中", "请勿重复提交")
+	IdempotencyKeyReused  = New(900422, "Idempotency-Key 已被其他请求使用", "请求参数与之前的请求不一致")
+)
@@ -0,0 +1,22 @@
+package ecode
+
+import "testing"
+
+func TestBuiltinRange(t *testing.T) {
+	// 业务沿用 100200 ~ 100600 定义的错误码不与内置错误码冲突
+	for _, code := range []int{100400, 100401, 100403, 100409, 100413, 100415, 100422, 100500, 100504} {
+		_em.mutex.RLock()
+		_, ok := _em.m[code]
+		_em.mutex.RUnlock()
+		if ok {
+			t.Errorf("expect code %d available for services", code)
+		}
+	}
+
+	for _, err := range []*E{ServerPanic, Timeout, InvalidParams, UnsupportedMediaType, RequestTooLarge,
+		Unauthorized, Forbidden, IdempotencyInProgress, IdempotencyKeyReused} {
+		if code := Code(err); code < 900000 || code > 900999 {
+			t.Errorf("expect builtin code in 900000 ~ 900999, but get %d", code)
+		}
+	}
+}
@@ -1,3 +1,6 @@
+// Package ecode 定义返回给客户端的错误码, 相同的错误码重复定义时 panic
+//
+// 900000 ~ 900999 为框架内置错误码的保留段, 见 common.go, 业务不要使用该段的错误码
 package ecode
 
 import (
@@ -7,11 +10,12 @@ import (
 
 // E implement Error interface
 type E struct {
-	code   int
-	stderr bool
-	msg    string
-	args   []interface{}
-	tips   tip
+	code    int
+	stderr  bool
+	msg     string
+	args    []interface{}
+	tips    tip
+	details interface{}
 }
 
 // New 添加一个不会标准输出的 error
@@ -73,11 +77,20 @@ func (e *E) FormatTips(args ...interface{}) *E {
 	return &clone
 }
 
+// WithDetails 附带返回给客户端的详细信息, 如参数校验失败的字段列表, xgin.Failure 将其作为 data 返回
+func (e *E) WithDetails(details interface{}) *E {
+	clone := e.Clone()
+	clone.args = e.args
+	clone.details = details
+	return &clone
+}
+
 func (e *E) Clone() E {
 	return E{
-		code:   e.code,
-		stderr: e.stderr,
-		msg:    e.msg,
+		code:    e.code,
+		stderr:  e.stderr,
+		msg:     e.msg,
+		details: e.details,
 		tips: tip{
 			tmpl: e.tips.tmpl,
 			args: e.tips.args,
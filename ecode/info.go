@@ -54,3 +54,11 @@ func Tips(err error) string {
 		return err.Error()
 	}
 }
+
+// Details 返回通过 WithDetails 附带的详细信息, 没有时返回 nil
+func Details(err error) interface{} {
+	if e, ok := err.(*E); ok {
+		return e.details
+	}
+	return nil
+}
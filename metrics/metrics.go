@@ -0,0 +1,180 @@
+// Package metrics 将 gin 路由, http 客户端, 数据库, redis 以及内存缓存的运行数据导出为 Prometheus 指标
+//
+// 所有指标注册在 Metrics 自己的 prometheus.Registry 中, 不使用全局的 DefaultRegisterer:
+//
+//	m := metrics.New(metrics.WithNamespace("order"))
+//	// 生产环境管理端口默认只监听 127.0.0.1, 需要被 Prometheus 抓取时通过 app.WithAdminAddr 绑定内网地址
+//	app.HandleAdmin("/metrics", m.Handler())
+//	engine.Use(m.Gin())
+//	client, _ := xhttp.NewClient(xhttp.WithRecorder(m.HTTPClient("user")))
+//	db := xmysql.NewORM(c, xmysql.WithRecorder(m.DB("main")), xmysql.WithOnOpen(m.DB("main").Watch))
+//	rdb := xredis.New(c, xredis.WithRecorder(m.Redis("cache")))
+//	cache := mcache.NewMCache(mcache.WithRecorder(m.Cache("config")))
+package metrics
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type Option func(*options)
+
+type options struct {
+	namespace string
+	buckets   []float64
+	runtime   bool
+}
+
+// WithNamespace 设置所有指标名称的前缀
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithBuckets 设置耗时直方图的分桶, 单位秒, 默认 prometheus.DefBuckets
+func WithBuckets(buckets ...float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// WithRuntime 是否注册 go 运行时和进程指标, 默认注册
+func WithRuntime(runtime bool) Option {
+	return func(o *options) {
+		o.runtime = runtime
+	}
+}
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	serverRequests *prometheus.CounterVec
+	serverDuration *prometheus.HistogramVec
+	serverInFlight *prometheus.GaugeVec
+
+	clientRequests *prometheus.CounterVec
+	clientDuration *prometheus.HistogramVec
+
+	dbQueries  *prometheus.CounterVec
+	dbDuration *prometheus.HistogramVec
+
+	redisCommands *prometheus.CounterVec
+	redisDuration *prometheus.HistogramVec
+
+	cacheRequests *prometheus.CounterVec
+
+	mu  sync.Mutex
+	dbs map[string]*DB
+}
+
+func New(opts ...Option) *Metrics {
+	o := options{
+		buckets: prometheus.DefBuckets,
+		runtime: true,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		serverRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "http_server_requests_total",
+			Help:      "Total number of HTTP requests handled, by route template.",
+		}, []string{"method", "route", "status"}),
+		serverDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "http_server_request_duration_seconds",
+			Help:      "HTTP request latency, by route template.",
+			Buckets:   o.buckets,
+		}, []string{"method", "route"}),
+		serverInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Name:      "http_server_requests_in_flight",
+			Help:      "Number of HTTP requests being handled, by route template.",
+		}, []string{"method", "route"}),
+
+		clientRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "http_client_requests_total",
+			Help:      "Total number of outbound HTTP requests, status is 0 when no response was received.",
+		}, []string{"client", "method", "status"}),
+		clientDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "http_client_request_duration_seconds",
+			Help:      "Outbound HTTP request latency.",
+			Buckets:   o.buckets,
+		}, []string{"client", "method"}),
+
+		dbQueries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "db_queries_total",
+			Help:      "Total number of sql statements executed.",
+		}, []string{"db", "operation", "result"}),
+		dbDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Sql statement latency.",
+			Buckets:   o.buckets,
+		}, []string{"db", "operation"}),
+
+		redisCommands: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "redis_commands_total",
+			Help:      "Total number of redis commands executed, pipelines are counted as one command.",
+		}, []string{"redis", "command", "result"}),
+		redisDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "redis_command_duration_seconds",
+			Help:      "Redis command latency.",
+			Buckets:   o.buckets,
+		}, []string{"redis", "command"}),
+
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "cache_requests_total",
+			Help:      "Total number of in-memory cache lookups, hit ratio is hit / (hit + miss).",
+		}, []string{"cache", "result"}),
+
+		dbs: make(map[string]*DB),
+	}
+
+	m.registry.MustRegister(
+		m.serverRequests, m.serverDuration, m.serverInFlight,
+		m.clientRequests, m.clientDuration,
+		m.dbQueries, m.dbDuration,
+		m.redisCommands, m.redisDuration,
+		m.cacheRequests,
+	)
+	if o.runtime {
+		m.registry.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		)
+	}
+	return m
+}
+
+// Registry 返回指标所在的 prometheus.Registry, 可以注册业务自定义的指标
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler 返回以 Prometheus 文本格式输出所有指标的 http.Handler, 一般通过 app.HandleAdmin 挂载到管理端口
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"skuld/database/mcache"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New(WithNamespace("test"), WithRuntime(false))
+
+	e := gin.New()
+	e.Use(m.Gin())
+	e.GET("/user/:id", func(c *gin.Context) {})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/1", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/2", nil))
+
+	cache := mcache.NewMCache(mcache.WithRecorder(m.Cache("config")))
+	cache.Set("a", 1)
+	cache.Get("a")
+	cache.Get("b")
+
+	m.DB("main").Record("select", time.Millisecond, nil)
+	m.Redis("cache").Record("get", time.Millisecond, errors.New("timeout"))
+	m.HTTPClient("user").Record(http.MethodGet, 0, time.Millisecond, errors.New("refused"))
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	for _, expect := range []string{
+		`test_http_server_requests_total{method="GET",route="/user/:id",status="200"} 2`,
+		`test_http_server_requests_in_flight{method="GET",route="/user/:id"} 0`,
+		`test_cache_requests_total{cache="config",result="hit"} 1`,
+		`test_cache_requests_total{cache="config",result="miss"} 1`,
+		`test_db_queries_total{db="main",operation="select",result="ok"} 1`,
+		`test_redis_commands_total{command="get",redis="cache",result="error"} 1`,
+		`test_http_client_requests_total{client="user",method="GET",status="0"} 1`,
+	} {
+		if !strings.Contains(body, expect) {
+			t.Errorf("expect %s in metrics output", expect)
+		}
+	}
+}
+
+func TestWatchTwice(t *testing.T) {
+	m := New()
+	db, err := sql.Open("mysql", "user:pass@tcp(127.0.0.1:3306)/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = m.DB("main").Watch(db); err != nil {
+		t.Fatal(err)
+	}
+	var already prometheus.AlreadyRegisteredError
+	if err = m.DB("main").Watch(db); !errors.As(err, &already) {
+		t.Errorf("expect AlreadyRegisteredError, but get %v", err)
+	}
+}
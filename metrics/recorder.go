@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"skuld/database/mcache"
+	"skuld/database/xredis"
+	xhttp "skuld/transport/http"
+)
+
+// Gin 返回记录请求数, 耗时和处理中请求数的中间件, 以路由模板 (如 /user/:id) 作为 route 标签, 未匹配的路由为 unmatched
+func (m *Metrics) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+
+		inFlight := m.serverInFlight.WithLabelValues(method, route)
+		inFlight.Inc()
+		begin := time.Now()
+		defer func() {
+			inFlight.Dec()
+			m.serverDuration.WithLabelValues(method, route).Observe(time.Since(begin).Seconds())
+			m.serverRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		}()
+
+		c.Next()
+	}
+}
+
+type httpClient struct {
+	m    *Metrics
+	name string
+}
+
+// HTTPClient 返回 transport/http.Client 的 Recorder, name 作为 client 标签
+func (m *Metrics) HTTPClient(name string) xhttp.Recorder {
+	return &httpClient{m: m, name: name}
+}
+
+func (h *httpClient) Record(method string, status int, d time.Duration, err error) {
+	h.m.clientRequests.WithLabelValues(h.name, method, strconv.Itoa(status)).Inc()
+	h.m.clientDuration.WithLabelValues(h.name, method).Observe(d.Seconds())
+}
+
+// DB 记录一个数据库的 sql 执行结果和连接池状态, 同时实现了 xsql.Recorder 和 xorm.Recorder
+type DB struct {
+	m    *Metrics
+	name string
+}
+
+// DB 返回名称为 name 的数据库指标, name 作为 db 标签, 相同 name 返回同一个 DB
+func (m *Metrics) DB(name string) *DB {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if db, ok := m.dbs[name]; ok {
+		return db
+	}
+	db := &DB{m: m, name: name}
+	m.dbs[name] = db
+	return db
+}
+
+func (d *DB) Record(op string, dur time.Duration, err error) {
+	d.m.dbQueries.WithLabelValues(d.name, op, result(err)).Inc()
+	d.m.dbDuration.WithLabelValues(d.name, op).Observe(dur.Seconds())
+}
+
+// Watch 注册 db 的连接池状态指标 (go_sql_*), 每个 name 只能注册一次, 重复注册时返回 prometheus.AlreadyRegisteredError
+func (d *DB) Watch(db *sql.DB) error {
+	return d.m.registry.Register(collectors.NewDBStatsCollector(db, d.name))
+}
+
+type redisRecorder struct {
+	m    *Metrics
+	name string
+}
+
+// Redis 返回 xredis 的 Recorder, name 作为 redis 标签
+func (m *Metrics) Redis(name string) xredis.Recorder {
+	return &redisRecorder{m: m, name: name}
+}
+
+func (r *redisRecorder) Record(cmd string, d time.Duration, err error) {
+	r.m.redisCommands.WithLabelValues(r.name, cmd, result(err)).Inc()
+	r.m.redisDuration.WithLabelValues(r.name, cmd).Observe(d.Seconds())
+}
+
+type cacheRecorder struct {
+	m    *Metrics
+	name string
+}
+
+// Cache 返回 mcache 的 Recorder, name 作为 cache 标签, result 标签为 hit / miss
+func (m *Metrics) Cache(name string) mcache.Recorder {
+	return &cacheRecorder{m: m, name: name}
+}
+
+func (c *cacheRecorder) Record(hit bool) {
+	if hit {
+		c.m.cacheRequests.WithLabelValues(c.name, "hit").Inc()
+	} else {
+		c.m.cacheRequests.WithLabelValues(c.name, "miss").Inc()
+	}
+}
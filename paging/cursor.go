@@ -0,0 +1,105 @@
+package paging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// cursor 游标的内容, 以 base64 (url) 编码的 json 传给客户端
+type cursor struct {
+	// Sort 生成游标时的排序, 与请求的排序不一致时游标无效
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+	// Times Values 中 time.Time 的下标, 解码时还原为 time.Time
+	Times []int `json:"t,omitempty"`
+}
+
+var errInvalidCursor = errors.New("paging: invalid cursor")
+
+func sortString(sorts []Sort) string {
+	parts := make([]string, 0, len(sorts))
+	for _, s := range sorts {
+		if s.Desc {
+			parts = append(parts, "-"+s.Column)
+		} else {
+			parts = append(parts, s.Column)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func encodeCursor(sorts []Sort, values []interface{}) (string, error) {
+	c := cursor{Sort: sortString(sorts), Values: values}
+	for i, v := range values {
+		if _, ok := v.(time.Time); ok {
+			c.Times = append(c.Times, i)
+		}
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(s string, sorts []Sort) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var c cursor
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// 数字保持原样, 避免大整数转为 float64 丢失精度
+	dec.UseNumber()
+	if err = dec.Decode(&c); err != nil {
+		return nil, errInvalidCursor
+	}
+	if c.Sort != sortString(sorts) || len(c.Values) != len(sorts) {
+		return nil, errInvalidCursor
+	}
+	for _, i := range c.Times {
+		if i < 0 || i >= len(c.Values) {
+			return nil, errInvalidCursor
+		}
+		str, ok := c.Values[i].(string)
+		if !ok {
+			return nil, errInvalidCursor
+		}
+		t, err := time.Parse(time.RFC3339Nano, str)
+		if err != nil {
+			return nil, errInvalidCursor
+		}
+		c.Values[i] = t
+	}
+	return c.Values, nil
+}
+
+// keyset 返回游标分页的条件, 如 -created_at,id 生成 (created_at < ? OR (created_at = ? AND id > ?)),
+// 排序列的值不能为 NULL
+func keyset(sorts []Sort, after []interface{}) (string, []interface{}) {
+	ors := make([]string, 0, len(sorts))
+	args := make([]interface{}, 0, len(sorts)*(len(sorts)+1)/2)
+	for i, s := range sorts {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, sorts[j].Column+" = ?")
+			args = append(args, after[j])
+		}
+		op := " > ?"
+		if s.Desc {
+			op = " < ?"
+		}
+		ands = append(ands, s.Column+op)
+		args = append(args, after[i])
+		if len(ands) == 1 {
+			ors = append(ors, ands[0])
+		} else {
+			ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+		}
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
@@ -0,0 +1,281 @@
+package paging
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"skuld/ecode"
+	"skuld/xgin"
+)
+
+// 过滤条件支持的操作符, 查询参数为 field=value (eq) 或 field[op]=value
+const (
+	OpEq   = "eq"
+	OpNe   = "ne"
+	OpGt   = "gt"
+	OpGte  = "gte"
+	OpLt   = "lt"
+	OpLte  = "lte"
+	OpLike = "like"
+	// OpIn 值以 "," 分隔
+	OpIn = "in"
+)
+
+var operators = map[string]string{
+	OpEq:   "=",
+	OpNe:   "<>",
+	OpGt:   ">",
+	OpGte:  ">=",
+	OpLt:   "<",
+	OpLte:  "<=",
+	OpLike: "LIKE",
+	OpIn:   "IN",
+}
+
+// Sort 排序字段, Column 为白名单中的列名
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// Filter 过滤条件, Column 为白名单中的列名, 除 OpIn 外 Values 只有一个值
+type Filter struct {
+	Column string
+	Op     string
+	Values []string
+}
+
+// Query 从请求中解析出的分页, 排序和过滤条件, Keyset 为 true 时使用游标分页, 忽略 Page
+type Query struct {
+	Page    int
+	Size    int
+	Sorts   []Sort
+	Filters []Filter
+	Keyset  bool
+	// After 游标中上一页最后一条记录的排序列的值, 与 Sorts 一一对应, 第一页为空
+	After []interface{}
+}
+
+// Offset 返回页码分页的偏移量
+func (q *Query) Offset() int {
+	return (q.Page - 1) * q.Size
+}
+
+// Page 分页响应, 页码分页返回 page 和 total, 游标分页返回 next_cursor, 请求下一页时作为 cursor 参数
+type Page struct {
+	Items      interface{} `json:"items" xml:"items" yaml:"items"`
+	Page       int         `json:"page,omitempty" xml:"page,omitempty" yaml:"page,omitempty"`
+	Size       int         `json:"size" xml:"size" yaml:"size"`
+	Total      *int64      `json:"total,omitempty" xml:"total,omitempty" yaml:"total,omitempty"`
+	HasMore    bool        `json:"has_more" xml:"has_more" yaml:"has_more"`
+	NextCursor string      `json:"next_cursor,omitempty" xml:"next_cursor,omitempty" yaml:"next_cursor,omitempty"`
+}
+
+// Success 通过 xgin.Success 返回分页响应
+func Success(c *gin.Context, p *Page) {
+	xgin.Success(c, p)
+}
+
+// Parser 从 gin 请求中解析 Query, 只允许按白名单中的字段排序和过滤
+type Parser struct {
+	pageKey      string
+	sizeKey      string
+	sortKey      string
+	cursorKey    string
+	defaultSize  int
+	maxSize      int
+	defaultSort  []Sort
+	sortFields   map[string]string
+	filterFields map[string]string
+	uniqueKey    string
+}
+
+type Option func(p *Parser)
+
+// WithSize 设置默认和最大的每页数量, 默认 20 和 100, 请求的 size 超过最大值时使用最大值
+func WithSize(def, max int) Option {
+	return func(p *Parser) {
+		p.defaultSize = def
+		p.maxSize = max
+	}
+}
+
+// WithSortFields 设置允许排序的字段, key 为查询参数中的字段名, value 为列名
+func WithSortFields(fields map[string]string) Option {
+	return func(p *Parser) {
+		p.sortFields = fields
+	}
+}
+
+// WithFilterFields 设置允许过滤的字段, key 为查询参数中的字段名, value 为列名
+func WithFilterFields(fields map[string]string) Option {
+	return func(p *Parser) {
+		p.filterFields = fields
+	}
+}
+
+// WithDefaultSort 设置请求没有 sort 参数时的排序, 格式同 sort 参数, 如 "-created_at,id", 字段需要在 WithSortFields 中
+func WithDefaultSort(sort string) Option {
+	return func(p *Parser) {
+		p.defaultSort = nil
+		for _, s := range strings.Split(sort, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			desc := strings.HasPrefix(s, "-")
+			p.defaultSort = append(p.defaultSort, Sort{Column: strings.TrimPrefix(s, "-"), Desc: desc})
+		}
+	}
+}
+
+// WithCursor 使用游标 (keyset) 分页代替页码分页, uniqueKey 为唯一列, 排序中不包含该列时追加到最后以保证顺序稳定
+func WithCursor(uniqueKey string) Option {
+	return func(p *Parser) {
+		p.uniqueKey = uniqueKey
+	}
+}
+
+// WithKeys 设置页码, 每页数量, 排序和游标的查询参数名, 默认 page / size / sort / cursor, 为空的参数名不修改
+func WithKeys(page, size, sort, cursor string) Option {
+	return func(p *Parser) {
+		if page != "" {
+			p.pageKey = page
+		}
+		if size != "" {
+			p.sizeKey = size
+		}
+		if sort != "" {
+			p.sortKey = sort
+		}
+		if cursor != "" {
+			p.cursorKey = cursor
+		}
+	}
+}
+
+func NewParser(opts ...Option) *Parser {
+	p := &Parser{
+		pageKey:     "page",
+		sizeKey:     "size",
+		sortKey:     "sort",
+		cursorKey:   "cursor",
+		defaultSize: 20,
+		maxSize:     100,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	for i, s := range p.defaultSort {
+		if column, ok := p.sortFields[s.Column]; ok {
+			p.defaultSort[i].Column = column
+		}
+	}
+	return p
+}
+
+// Parse 解析请求的查询参数, 参数不合法时返回附带 []xgin.FieldError 的 ecode.InvalidParams, 可以直接交给 xgin.Failure
+//
+//	GET /users?page=2&size=10&sort=-created_at,name&status=1&age[gte]=18&name[like]=tom&role[in]=admin,owner
+func (p *Parser) Parse(c *gin.Context) (*Query, error) {
+	values := c.Request.URL.Query()
+	q := &Query{Page: 1, Size: p.defaultSize, Keyset: p.uniqueKey != ""}
+
+	if v := values.Get(p.pageKey); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return nil, invalid(p.pageKey, "min", "1", fmt.Sprintf("%s必须是大于 0 的整数", p.pageKey))
+		}
+		q.Page = page
+	}
+	if v := values.Get(p.sizeKey); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 1 {
+			return nil, invalid(p.sizeKey, "min", "1", fmt.Sprintf("%s必须是大于 0 的整数", p.sizeKey))
+		}
+		q.Size = size
+	}
+	if q.Size > p.maxSize {
+		q.Size = p.maxSize
+	}
+
+	if v := values.Get(p.sortKey); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			name := strings.TrimPrefix(s, "-")
+			column, ok := p.sortFields[name]
+			if !ok {
+				return nil, invalid(p.sortKey, "oneof", keys(p.sortFields), fmt.Sprintf("不支持按%s排序", name))
+			}
+			q.Sorts = append(q.Sorts, Sort{Column: column, Desc: strings.HasPrefix(s, "-")})
+		}
+	} else {
+		q.Sorts = append(q.Sorts, p.defaultSort...)
+	}
+	if q.Keyset {
+		if !hasColumn(q.Sorts, p.uniqueKey) {
+			q.Sorts = append(q.Sorts, Sort{Column: p.uniqueKey})
+		}
+		if v := values.Get(p.cursorKey); v != "" {
+			after, err := decodeCursor(v, q.Sorts)
+			if err != nil {
+				return nil, invalid(p.cursorKey, "cursor", "", fmt.Sprintf("%s无效或与排序不一致", p.cursorKey))
+			}
+			q.After = after
+		}
+	}
+
+	for key, vs := range values {
+		name, op := key, OpEq
+		if i := strings.Index(key, "["); i > 0 && strings.HasSuffix(key, "]") {
+			name, op = key[:i], key[i+1:len(key)-1]
+		}
+		column, ok := p.filterFields[name]
+		if !ok {
+			continue
+		}
+		if _, ok = operators[op]; !ok {
+			return nil, invalid(key, "oneof", "eq ne gt gte lt lte like in", fmt.Sprintf("%s不支持操作符%s", name, op))
+		}
+		f := Filter{Column: column, Op: op, Values: vs[:1]}
+		if op == OpIn {
+			f.Values = strings.Split(vs[0], ",")
+		}
+		q.Filters = append(q.Filters, f)
+	}
+	// map 遍历无序, 排序后生成的 sql 保持稳定
+	sort.SliceStable(q.Filters, func(i, j int) bool {
+		if q.Filters[i].Column != q.Filters[j].Column {
+			return q.Filters[i].Column < q.Filters[j].Column
+		}
+		return q.Filters[i].Op < q.Filters[j].Op
+	})
+	return q, nil
+}
+
+func hasColumn(sorts []Sort, column string) bool {
+	for _, s := range sorts {
+		if s.Column == column {
+			return true
+		}
+	}
+	return false
+}
+
+func keys(m map[string]string) string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return strings.Join(ks, " ")
+}
+
+func invalid(field, rule, param, msg string) error {
+	return ecode.InvalidParams.SetMsg(msg).WithDetails([]xgin.FieldError{{Field: field, Rule: rule, Param: param, Message: msg}})
+}
@@ -0,0 +1,108 @@
+package paging
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"skuld/ecode"
+)
+
+func parse(t *testing.T, p *Parser, query string) (*Query, error) {
+	t.Helper()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+	return p.Parse(c)
+}
+
+func TestParse(t *testing.T) {
+	p := NewParser(
+		WithSize(10, 50),
+		WithSortFields(map[string]string{"created_at": "created_at", "name": "users.name"}),
+		WithFilterFields(map[string]string{"status": "status", "age": "age", "role": "role"}),
+		WithDefaultSort("-created_at"),
+	)
+
+	q, err := parse(t, p, "page=3&size=200&sort=-name,created_at&status=1&age[gte]=18&role[in]=admin,owner&other=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 3 || q.Size != 50 || q.Offset() != 100 {
+		t.Errorf("unexpect page %d size %d offset %d", q.Page, q.Size, q.Offset())
+	}
+	if sorts := []Sort{{Column: "users.name", Desc: true}, {Column: "created_at"}}; !reflect.DeepEqual(q.Sorts, sorts) {
+		t.Errorf("expect sorts %v, but get %v", sorts, q.Sorts)
+	}
+	filters := []Filter{
+		{Column: "age", Op: OpGte, Values: []string{"18"}},
+		{Column: "role", Op: OpIn, Values: []string{"admin", "owner"}},
+		{Column: "status", Op: OpEq, Values: []string{"1"}},
+	}
+	if !reflect.DeepEqual(q.Filters, filters) {
+		t.Errorf("expect filters %v, but get %v", filters, q.Filters)
+	}
+
+	q, err = parse(t, p, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 1 || q.Size != 10 || !reflect.DeepEqual(q.Sorts, []Sort{{Column: "created_at", Desc: true}}) {
+		t.Errorf("unexpect default query %+v", q)
+	}
+
+	for _, query := range []string{"sort=password", "page=0", "size=abc", "age[regexp]=1"} {
+		if _, err = parse(t, p, query); ecode.Code(err) != ecode.Code(ecode.InvalidParams) {
+			t.Errorf("%s: expect invalid params, but get %v", query, err)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	p := NewParser(
+		WithSortFields(map[string]string{"created_at": "created_at"}),
+		WithDefaultSort("-created_at"),
+		WithCursor("id"),
+	)
+	q, err := parse(t, p, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorts := []Sort{{Column: "created_at", Desc: true}, {Column: "id"}}
+	if !q.Keyset || !reflect.DeepEqual(q.Sorts, sorts) {
+		t.Fatalf("unexpect keyset query %+v", q)
+	}
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cur, err := encodeCursor(q.Sorts, []interface{}{createdAt, uint64(9007199254740993)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err = parse(t, p, "cursor="+cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at, ok := q.After[0].(time.Time); !ok || !at.Equal(createdAt) {
+		t.Errorf("expect created_at %v, but get %v", createdAt, q.After[0])
+	}
+	if id := fmt.Sprint(q.After[1]); id != "9007199254740993" {
+		t.Errorf("expect id 9007199254740993, but get %v", id)
+	}
+
+	query, args := keyset(q.Sorts, q.After)
+	if expect := "(created_at < ? OR (created_at = ? AND id > ?))"; query != expect {
+		t.Errorf("expect %s, but get %s", expect, query)
+	}
+	if len(args) != 3 {
+		t.Errorf("expect 3 args, but get %d", len(args))
+	}
+
+	// 排序改变后游标无效
+	if _, err = parse(t, p, "sort=created_at&cursor="+cur); ecode.Code(err) != ecode.Code(ecode.InvalidParams) {
+		t.Errorf("expect invalid params, but get %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package paging
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+
+	"skuld/xorm"
+)
+
+// schemaCaches 命名策略 -> 解析后的 schema 缓存
+var schemaCaches sync.Map
+
+// Where 将 q 的过滤条件和游标条件添加到 db
+func Where(ctx context.Context, db xorm.Core, q *Query) xorm.Core {
+	for _, f := range q.Filters {
+		switch f.Op {
+		case OpIn:
+			db = db.Where(ctx, f.Column+" IN ?", f.Values)
+		case OpLike:
+			db = db.Where(ctx, f.Column+" LIKE ?", "%"+escapeLike(f.Values[0])+"%")
+		default:
+			db = db.Where(ctx, f.Column+" "+operators[f.Op]+" ?", f.Values[0])
+		}
+	}
+	if q.Keyset && len(q.After) > 0 {
+		query, args := keyset(q.Sorts, q.After)
+		db = db.Where(ctx, query, args...)
+	}
+	return db
+}
+
+// Order 将 q 的排序添加到 db
+func Order(ctx context.Context, db xorm.Core, q *Query) xorm.Core {
+	for _, s := range q.Sorts {
+		if s.Desc {
+			db = db.Order(ctx, s.Column+" DESC")
+		} else {
+			db = db.Order(ctx, s.Column)
+		}
+	}
+	return db
+}
+
+// Find 按 q 查询到 dest (结构体切片的指针) 并返回分页响应, db 需要通过 Model 或 Table 指定表;
+// 页码分页同时查询过滤后的总数, 游标分页多查询一条判断是否有下一页, 并根据最后一条记录生成 next_cursor
+//
+//	q, err := parser.Parse(c)
+//	if err != nil {
+//		xgin.Failure(c, err)
+//		return
+//	}
+//	var users []User
+//	page, err := paging.Find(ctx, orm.Model(ctx, &User{}), q, &users)
+//	if err != nil {
+//		xgin.Failure(c, err)
+//		return
+//	}
+//	paging.Success(c, page)
+func Find(ctx context.Context, db xorm.Core, q *Query, dest interface{}) (*Page, error) {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return nil, errors.New("paging: dest must be a pointer to slice")
+	}
+	db = Where(ctx, db, q)
+	page := &Page{Items: dest, Size: q.Size}
+
+	if !q.Keyset {
+		var total int64
+		if err := db.Count(ctx, &total); err != nil {
+			return nil, err
+		}
+		page.Page = q.Page
+		page.Total = &total
+		page.HasMore = int64(q.Offset()+q.Size) < total
+		if err := Order(ctx, db, q).Offset(ctx, q.Offset()).Limit(ctx, q.Size).Find(ctx, dest); err != nil {
+			return nil, err
+		}
+		return page, nil
+	}
+
+	if err := Order(ctx, db, q).Limit(ctx, q.Size+1).Find(ctx, dest); err != nil {
+		return nil, err
+	}
+	items := rv.Elem()
+	if items.Len() <= q.Size {
+		return page, nil
+	}
+	items.Set(items.Slice(0, q.Size))
+	page.HasMore = true
+	values, err := sortValues(ctx, xorm.Namer(db), dest, items.Index(q.Size-1), q.Sorts)
+	if err != nil {
+		return nil, err
+	}
+	if page.NextCursor, err = encodeCursor(q.Sorts, values); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// sortValues 按 namer 解析 dest 的 schema, 返回记录中排序列的值, 列名可以带表名前缀, 如 users.id
+func sortValues(ctx context.Context, namer schema.Namer, dest interface{}, item reflect.Value, sorts []Sort) ([]interface{}, error) {
+	cache := &sync.Map{}
+	// 不同的命名策略解析出的列名不同, 分别缓存; 不可比较的命名策略不缓存
+	if reflect.TypeOf(namer).Comparable() {
+		v, _ := schemaCaches.LoadOrStore(namer, cache)
+		cache = v.(*sync.Map)
+	}
+	s, err := schema.Parse(dest, cache, namer)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, 0, len(sorts))
+	for _, sort := range sorts {
+		column := sort.Column
+		if i := strings.LastIndex(column, "."); i >= 0 {
+			column = column[i+1:]
+		}
+		field := s.LookUpField(strings.Trim(column, "`\""))
+		if field == nil {
+			return nil, errors.New("paging: field of column " + sort.Column + " not found")
+		}
+		v, _ := field.ValueOf(ctx, item)
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+var likeReplacer = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike 转义 LIKE 中的通配符, 按字面量匹配
+func escapeLike(s string) string {
+	return likeReplacer.Replace(s)
+}
@@ -0,0 +1,175 @@
+package paging
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"skuld/xorm"
+)
+
+type user struct {
+	ID       uint64
+	UserName string
+}
+
+// fakeCore 记录 paging 调用的查询条件, Find 返回预设的记录, 其他方法未实现
+type fakeCore struct {
+	xorm.Core
+	calls *[]string
+	rows  []user
+	total int64
+}
+
+func (f fakeCore) record(format string, args ...interface{}) fakeCore {
+	*f.calls = append(*f.calls, fmt.Sprintf(format, args...))
+	return f
+}
+
+func (f fakeCore) Where(ctx context.Context, query interface{}, args ...interface{}) xorm.Core {
+	return f.record("where %v %v", query, args)
+}
+
+func (f fakeCore) Order(ctx context.Context, value interface{}) xorm.Core {
+	return f.record("order %v", value)
+}
+
+func (f fakeCore) Offset(ctx context.Context, offset int) xorm.Core {
+	return f.record("offset %d", offset)
+}
+
+func (f fakeCore) Limit(ctx context.Context, limit int) xorm.Core {
+	return f.record("limit %d", limit)
+}
+
+func (f fakeCore) Count(ctx context.Context, count *int64) error {
+	f.record("count")
+	*count = f.total
+	return nil
+}
+
+func (f fakeCore) Find(ctx context.Context, dest interface{}, conds ...interface{}) error {
+	f.record("find")
+	rows := f.rows
+	for _, call := range *f.calls {
+		var limit int
+		if _, err := fmt.Sscanf(call, "limit %d", &limit); err == nil && limit < len(rows) {
+			rows = rows[:limit]
+		}
+	}
+	*dest.(*[]user) = append([]user(nil), rows...)
+	return nil
+}
+
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+	rows := []user{{ID: 1, UserName: "a"}, {ID: 2, UserName: "b"}, {ID: 3, UserName: "c"}}
+
+	var calls []string
+	q := &Query{
+		Page:  2,
+		Size:  2,
+		Sorts: []Sort{{Column: "user_name", Desc: true}},
+		Filters: []Filter{
+			{Column: "role", Op: OpIn, Values: []string{"admin", "owner"}},
+			{Column: "user_name", Op: OpLike, Values: []string{"50%_off"}},
+			{Column: "id", Op: OpGte, Values: []string{"1"}},
+		},
+	}
+	var users []user
+	page, err := Find(ctx, fakeCore{calls: &calls, rows: rows, total: 5}, q, &users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"where role IN ? [[admin owner]]",
+		`where user_name LIKE ? [%50\%\_off%]`,
+		"where id >= ? [1]",
+		"count",
+		"order user_name DESC",
+		"offset 2",
+		"limit 2",
+		"find",
+	}
+	if !reflect.DeepEqual(calls, expect) {
+		t.Errorf("expect calls %q, but get %q", expect, calls)
+	}
+	if page.Page != 2 || page.Total == nil || *page.Total != 5 || !page.HasMore || len(users) != 2 {
+		t.Errorf("unexpect page %+v", page)
+	}
+
+	// 游标分页多查询一条判断是否有下一页, 并以最后一条记录生成 next_cursor
+	calls = nil
+	q = &Query{Size: 2, Sorts: []Sort{{Column: "users.user_name"}, {Column: "id"}}, Keyset: true,
+		After: []interface{}{"0", uint64(0)}}
+	users = nil
+	page, err = Find(ctx, fakeCore{calls: &calls, rows: rows}, q, &users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = []string{
+		"where (users.user_name > ? OR (users.user_name = ? AND id > ?)) [0 0 0]",
+		"order users.user_name",
+		"order id",
+		"limit 3",
+		"find",
+	}
+	if !reflect.DeepEqual(calls, expect) {
+		t.Errorf("expect calls %q, but get %q", expect, calls)
+	}
+	if !page.HasMore || page.Total != nil || len(users) != 2 || users[1].ID != 2 {
+		t.Fatalf("unexpect page %+v users %v", page, users)
+	}
+	after, err := decodeCursor(page.NextCursor, q.Sorts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(after) != "[b 2]" {
+		t.Errorf("expect cursor values [b 2], but get %v", after)
+	}
+
+	// 最后一页不返回 next_cursor
+	calls = nil
+	users = nil
+	page, err = Find(ctx, fakeCore{calls: &calls, rows: rows[:2]}, &Query{Size: 2, Sorts: q.Sorts, Keyset: true}, &users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.HasMore || page.NextCursor != "" || len(users) != 2 {
+		t.Errorf("unexpect last page %+v", page)
+	}
+}
+
+func TestSortValuesNamer(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "root@tcp(127.0.0.1:3306)/test", SkipInitializeWithVersion: true}),
+		&gorm.Config{
+			DryRun:               true,
+			DisableAutomaticPing: true,
+			NamingStrategy:       schema.NamingStrategy{NameReplacer: strings.NewReplacer("UserName", "Login")},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := []user{{ID: 1, UserName: "a"}}
+	item := reflect.ValueOf(users).Index(0)
+	sorts := []Sort{{Column: "login"}}
+	values, err := sortValues(context.Background(), xorm.Namer(xorm.New(db)), &users, item, sorts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(values) != "[a]" {
+		t.Errorf("expect [a], but get %v", values)
+	}
+
+	// 默认命名策略下列名为 user_name
+	if _, err := sortValues(context.Background(), schema.NamingStrategy{}, &users, item, sorts); err == nil {
+		t.Error("expect column login not found with default naming strategy")
+	}
+}
@@ -0,0 +1,52 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	type testCase struct {
+		name   string
+		id     string
+		expect bool
+	}
+
+	testTable := []testCase{
+		{name: "hex", id: New(), expect: true},
+		{name: "printable", id: "order-1_a.b:c", expect: true},
+		{name: "max length", id: strings.Repeat("a", maxLen), expect: true},
+		{name: "empty", id: "", expect: false},
+		{name: "too long", id: strings.Repeat("a", maxLen+1), expect: false},
+		{name: "space", id: "a b", expect: false},
+		{name: "newline", id: "a\nb", expect: false},
+		{name: "control", id: "a\x00b", expect: false},
+		{name: "del", id: "a\x7fb", expect: false},
+		{name: "non ascii", id: "请求", expect: false},
+	}
+
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			if got := Valid(v.id); got != v.expect {
+				t.Fatalf("expect %v, but get %v", v.expect, got)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	a, b := New(), New()
+	if len(a) != 32 || a == b {
+		t.Fatalf("expect unique 32 chars id, but get %q %q", a, b)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if id := FromContext(context.Background()); id != "" {
+		t.Fatalf("expect empty id, but get %q", id)
+	}
+	if id := FromContext(NewContext(context.Background(), "rid")); id != "rid" {
+		t.Fatalf("expect rid, but get %q", id)
+	}
+}
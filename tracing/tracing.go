@@ -0,0 +1,95 @@
+// Package tracing 创建兼容 OpenTelemetry 的 TracerProvider, 并提供离线可用的 stdout / 内存 exporter
+//
+// 各组件通过选项接收 trace.TracerProvider, 不使用 otel 的全局 provider:
+//
+//	exporter, err := tracing.NewStdoutExporter(os.Stdout)
+//	if err != nil {
+//		panic(err)
+//	}
+//	tp := tracing.NewProvider(tracing.WithServiceName("order"), tracing.WithExporter(exporter))
+//	defer tp.Shutdown(context.Background())
+//	engine.Use(xmiddleware.RequestID, xmiddleware.Tracing(tp), xmiddleware.Log(logger))
+//	client, _ := xhttp.NewClient(xhttp.WithTracerProvider(tp))
+//	db := xmysql.NewORM(c, xmysql.WithTracerProvider(tp))
+package tracing
+
+import (
+	"io"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type Option func(*options)
+
+type options struct {
+	serviceName string
+	exporters   []sdktrace.SpanExporter
+	syncers     []sdktrace.SpanExporter
+	ratio       float64
+}
+
+// WithServiceName 设置 service.name 资源属性
+func WithServiceName(name string) Option {
+	return func(o *options) {
+		o.serviceName = name
+	}
+}
+
+// WithExporter 批量异步导出 span, 可以设置多个
+func WithExporter(e sdktrace.SpanExporter) Option {
+	return func(o *options) {
+		o.exporters = append(o.exporters, e)
+	}
+}
+
+// WithSyncer span 结束时同步导出, 一般用于测试和调试
+func WithSyncer(e sdktrace.SpanExporter) Option {
+	return func(o *options) {
+		o.syncers = append(o.syncers, e)
+	}
+}
+
+// WithSampleRatio 设置没有上游采样决定时的采样比例, 上游已采样的请求始终采样, 默认 1
+func WithSampleRatio(ratio float64) Option {
+	return func(o *options) {
+		o.ratio = ratio
+	}
+}
+
+// NewProvider 创建 TracerProvider, 退出前需要调用 Shutdown 导出剩余的 span
+func NewProvider(opts ...Option) *sdktrace.TracerProvider {
+	o := options{ratio: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	tpOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(o.ratio))),
+	}
+	if o.serviceName != "" {
+		tpOpts = append(tpOpts, sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", o.serviceName),
+		)))
+	}
+	for _, e := range o.exporters {
+		tpOpts = append(tpOpts, sdktrace.WithBatcher(e))
+	}
+	for _, e := range o.syncers {
+		tpOpts = append(tpOpts, sdktrace.WithSyncer(e))
+	}
+	return sdktrace.NewTracerProvider(tpOpts...)
+}
+
+// NewStdoutExporter 以 json 格式将 span 写入 w
+func NewStdoutExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(w))
+}
+
+// NewMemoryExporter 将 span 保存在内存中, 通过 GetSpans 读取, 一般配合 WithSyncer 用于测试
+func NewMemoryExporter() *tracetest.InMemoryExporter {
+	return tracetest.NewInMemoryExporter()
+}
@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	_ "skuld/encoding/json"
+	xhttp "skuld/transport/http"
+	"skuld/xgin/xmiddleware"
+	"skuld/xlogger/xloggertest"
+)
+
+func TestTracing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	exporter := NewMemoryExporter()
+	tp := NewProvider(WithServiceName("test"), WithSyncer(exporter))
+	defer tp.Shutdown(context.Background())
+	logger := xloggertest.NewT(t)
+
+	// 下游服务
+	downstream := gin.New()
+	downstream.Use(xmiddleware.Tracing(tp))
+	downstream.GET("/user/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, map[string]interface{}{})
+	})
+	srv := httptest.NewServer(downstream)
+	defer srv.Close()
+
+	client, _ := xhttp.NewClient(xhttp.WithEndpoint(srv.URL), xhttp.WithTracerProvider(tp))
+	e := gin.New()
+	e.Use(xmiddleware.Tracing(tp), xmiddleware.Log(logger))
+	e.GET("/order/:id", func(c *gin.Context) {
+		var reply map[string]interface{}
+		if err := client.Get(c.Request.Context(), "/user/1", &reply); err != nil {
+			t.Error(err)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req.Header.Set("traceparent", parent)
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expect 3 spans, but get %d", len(spans))
+	}
+	names := map[string]trace.SpanKind{}
+	for _, s := range spans {
+		names[s.Name] = s.SpanKind
+		if s.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("span %s should continue the upstream trace, but get %s", s.Name, s.SpanContext.TraceID())
+		}
+	}
+	if names["GET /user/:id"] != trace.SpanKindServer || names["HTTP GET"] != trace.SpanKindClient ||
+		names["GET /order/:id"] != trace.SpanKindServer {
+		t.Errorf("unexpected spans: %v", names)
+	}
+	// 下游的 server span 以 client span 为父节点
+	if spans[0].Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Errorf("downstream span should be a child of the client span")
+	}
+
+	logger.AssertLogged(t, xloggertest.InfoLevel, "access log", "trace_id", "4bf92f3577b34da6a3ce929d0e0e4736")
+}
+
+func TestStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	exporter, err := NewStdoutExporter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp := NewProvider(WithServiceName("test"), WithSyncer(exporter))
+	_, span := tp.Tracer("test").Start(context.Background(), "stdout span")
+	span.End()
+	if err = tp.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"Name":"stdout span"`) {
+		t.Errorf("expect span written, but get %s", buf.String())
+	}
+}
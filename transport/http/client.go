@@ -10,7 +10,15 @@ import (
 	"net/http"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"skuld/deadline"
 	"skuld/encoding"
+	"skuld/requestid"
 )
 
 type DecodeErrorFunc func(ctx context.Context, res *http.Response) error
@@ -30,6 +38,15 @@ type clientOptions struct {
 	decoder      DecodeResponseFunc
 	errorDecoder DecodeErrorFunc
 	transport    http.RoundTripper
+	recorder     Recorder
+	tracer       trace.TracerProvider
+	deadline     bool
+	latency      time.Duration
+}
+
+// Recorder 记录每次请求的方法, 状态码, 耗时和结果, 请求未得到响应时 status 为 0, 可以实现该接口将数据导出为监控指标
+type Recorder interface {
+	Record(method string, status int, d time.Duration, err error)
 }
 
 func WithTlsConfig(cfg *tls.Config) ClientOption {
@@ -80,9 +97,33 @@ func WithTransport(trans *http.Transport) ClientOption {
 	}
 }
 
+func WithRecorder(r Recorder) ClientOption {
+	return func(o *clientOptions) {
+		o.recorder = r
+	}
+}
+
+// WithTracerProvider 为每次请求创建 client span, 并通过 W3C traceparent 请求头传递给下游
+func WithTracerProvider(tp trace.TracerProvider) ClientOption {
+	return func(o *clientOptions) {
+		o.tracer = tp
+	}
+}
+
+// WithDeadlinePropagation 通过 deadline.Header 将剩余时间 (ctx 的截止时间和 client 超时时间中较短的一个) 减去 latency 传给下游,
+// 下游的 xmiddleware.Timeout 据此设置超时; latency 为预留的网络传输时间, 使下游在本端超时前返回,
+// 减去 latency 后不足 1 毫秒时不发送请求, 直接返回 context.DeadlineExceeded. 只应对信任的内部服务开启
+func WithDeadlinePropagation(latency time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.deadline = true
+		o.latency = latency
+	}
+}
+
 type Client struct {
-	opts clientOptions
-	cc   *http.Client
+	opts   clientOptions
+	cc     *http.Client
+	tracer trace.Tracer
 }
 
 func NewClient(opts ...ClientOption) (*Client, error) {
@@ -92,6 +133,7 @@ func NewClient(opts ...ClientOption) (*Client, error) {
 		decoder:      DefaultResponseDecoder,
 		errorDecoder: DefaultErrorDecoder,
 		transport:    http.DefaultTransport,
+		tracer:       noop.NewTracerProvider(),
 	}
 
 	for _, option := range opts {
@@ -110,6 +152,7 @@ func NewClient(opts ...ClientOption) (*Client, error) {
 			Timeout:   options.timeout,
 			Transport: options.transport,
 		},
+		tracer: options.tracer.Tracer("skuld/transport/http"),
 	}, nil
 }
 
@@ -155,10 +198,13 @@ func (client *Client) BuildRequest(ctx context.Context, method, path, contentTyp
 		body = bytes.NewReader(data)
 	}
 	url := fmt.Sprintf("%s%s", client.opts.endpoint, path)
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
 	}
@@ -177,15 +223,55 @@ func (client *Client) BuildResponse(ctx context.Context, res *http.Response, rep
 }
 
 func (client *Client) Do(req *http.Request) (*http.Response, error) {
+	if id := requestid.FromContext(req.Context()); id != "" && req.Header.Get(requestid.Header) == "" {
+		req.Header.Set(requestid.Header, id)
+	}
 	return client.do(req.Context(), req)
 }
 
 func (client *Client) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if client.opts.deadline && req.Header.Get(deadline.Header) == "" {
+		budget, ok := deadline.Remaining(req.Context())
+		if t := client.opts.timeout; t > 0 && (!ok || t < budget) {
+			budget, ok = t, true
+		}
+		if ok {
+			// 下游收到 0 毫秒的剩余时间会直接返回超时, 不必发送请求
+			if budget -= client.opts.latency; budget < time.Millisecond {
+				return nil, context.DeadlineExceeded
+			}
+			req.Header.Set(deadline.Header, deadline.Format(budget))
+		}
+	}
+	spanCtx, span := client.tracer.Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.full", req.URL.String()),
+			attribute.String("server.address", req.URL.Host),
+		),
+	)
+	defer span.End()
+	propagation.TraceContext{}.Inject(spanCtx, propagation.HeaderCarrier(req.Header))
+	req = req.WithContext(spanCtx)
+
+	begin := time.Now()
 	resp, err := client.cc.Do(req)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+	}
+	if r := client.opts.recorder; r != nil {
+		r.Record(req.Method, status, time.Since(begin), err)
+	}
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
 	if err = client.opts.errorDecoder(ctx, resp); err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
 	return resp, nil
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"skuld/deadline"
+	_ "skuld/encoding/json"
+	"skuld/requestid"
+)
+
+func TestRequestIDForwarding(t *testing.T) {
+	ids := make(chan []string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids <- r.Header.Values(requestid.Header)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(WithEndpoint(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := requestid.NewContext(context.Background(), "rid")
+
+	// Invoke 通过 BuildRequest 附带 ctx 中的请求 ID
+	var reply map[string]interface{}
+	if err = client.Get(ctx, "/", &reply); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-ids; len(got) != 1 || got[0] != "rid" {
+		t.Errorf("expect request id rid, but get %v", got)
+	}
+
+	// Do 为自行构造的请求补充请求 ID
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := <-ids; len(got) != 1 || got[0] != "rid" {
+		t.Errorf("expect request id rid, but get %v", got)
+	}
+
+	// 已设置的请求 ID 不被覆盖
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	req.Header.Set(requestid.Header, "custom")
+	if resp, err = client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := <-ids; len(got) != 1 || got[0] != "custom" {
+		t.Errorf("expect request id custom, but get %v", got)
+	}
+
+	// ctx 中没有请求 ID 时不设置
+	if err = client.Get(context.Background(), "/", &reply); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-ids; len(got) != 0 {
+		t.Errorf("expect no request id, but get %v", got)
+	}
+}
+
+func TestDeadlinePropagation(t *testing.T) {
+	headers := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Get(deadline.Header)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(WithEndpoint(srv.URL), WithTimeout(time.Second), WithDeadlinePropagation(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name    string
+		timeout time.Duration
+		min     int64
+		max     int64
+	}
+	testTable := []testCase{
+		// ctx 没有截止时间时使用 client 的超时时间
+		{name: "client timeout", min: 900, max: 950},
+		{name: "ctx deadline", timeout: 300 * time.Millisecond, min: 200, max: 250},
+	}
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			ctx := context.Background()
+			if v.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, v.timeout)
+				defer cancel()
+			}
+			var reply map[string]interface{}
+			if err := client.Get(ctx, "/", &reply); err != nil {
+				t.Fatal(err)
+			}
+			ms, err := strconv.ParseInt(<-headers, 10, 64)
+			if err != nil || ms < v.min || ms > v.max {
+				t.Errorf("expect %s in [%d, %d], but get %d (%v)", deadline.Header, v.min, v.max, ms, err)
+			}
+		})
+	}
+
+	// 剩余时间不足 latency 时不发送请求
+	ctx, cancel := context.WithTimeout(context.Background(), 40*time.Millisecond)
+	defer cancel()
+	var reply map[string]interface{}
+	if err = client.Get(ctx, "/", &reply); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expect context.DeadlineExceeded, but get %v", err)
+	}
+	select {
+	case h := <-headers:
+		t.Errorf("expect no request sent, but get header %q", h)
+	default:
+	}
+}
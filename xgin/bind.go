@@ -0,0 +1,244 @@
+package xgin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"skuld/ecode"
+	"skuld/encoding"
+	_ "skuld/encoding/form"
+	_ "skuld/encoding/json"
+	_ "skuld/encoding/xml"
+	_ "skuld/encoding/yaml"
+)
+
+var maxBodySize int64 = 10 << 20
+
+// SetMaxBodySize 设置 Bind 读取 body 的最大字节数, 默认 10MB, 包括 multipart 表单中的文件
+func SetMaxBodySize(n int64) {
+	atomic.StoreInt64(&maxBodySize, n)
+}
+
+// Bind 依次将路径参数 (uri tag), 查询参数 (form tag), 请求头 (header tag) 以及 body 绑定到 v, 然后根据 binding tag 校验,
+// 表单 body 使用 form tag, 其他 body 根据 Content-Type 使用 encoding 中注册的 Codec 解析;
+// 失败时返回 ecode.InvalidParams (校验或类型转换失败时附带 []FieldError), ecode.UnsupportedMediaType 或 ecode.RequestTooLarge,
+// 可以直接交给 Failure
+//
+//	type Req struct {
+//		ID    int    `uri:"id" binding:"required"`
+//		Page  int    `form:"page" binding:"min=1"`
+//		Token string `header:"X-Token" binding:"required"`
+//		Name  string `json:"name" binding:"required,max=20"`
+//	}
+func Bind(c *gin.Context, v interface{}) error {
+	if err := bindURI(c, v); err != nil {
+		return err
+	}
+	if err := bindQuery(c, v); err != nil {
+		return err
+	}
+	if err := bindHeader(c, v); err != nil {
+		return err
+	}
+	if err := bindBody(c, v); err != nil {
+		return err
+	}
+	return Validate(c, v)
+}
+
+// BindURI 只绑定并校验路径参数
+func BindURI(c *gin.Context, v interface{}) error {
+	if err := bindURI(c, v); err != nil {
+		return err
+	}
+	return Validate(c, v)
+}
+
+// BindQuery 只绑定并校验查询参数
+func BindQuery(c *gin.Context, v interface{}) error {
+	if err := bindQuery(c, v); err != nil {
+		return err
+	}
+	return Validate(c, v)
+}
+
+// BindHeader 只绑定并校验请求头
+func BindHeader(c *gin.Context, v interface{}) error {
+	if err := bindHeader(c, v); err != nil {
+		return err
+	}
+	return Validate(c, v)
+}
+
+// BindBody 只绑定并校验 body
+func BindBody(c *gin.Context, v interface{}) error {
+	if err := bindBody(c, v); err != nil {
+		return err
+	}
+	return Validate(c, v)
+}
+
+func bindURI(c *gin.Context, v interface{}) error {
+	if len(c.Params) == 0 {
+		return nil
+	}
+	params := make(map[string][]string, len(c.Params))
+	for _, p := range c.Params {
+		params[p.Key] = []string{p.Value}
+	}
+	return mapForm(c, v, params, "uri")
+}
+
+func bindQuery(c *gin.Context, v interface{}) error {
+	query := c.Request.URL.Query()
+	if len(query) == 0 {
+		return nil
+	}
+	return mapForm(c, v, query, "form")
+}
+
+func bindHeader(c *gin.Context, v interface{}) error {
+	if len(c.Request.Header) == 0 {
+		return nil
+	}
+	// header tag 可能是任意大小写, 同时以规范形式和小写形式作为 key
+	header := make(map[string][]string, len(c.Request.Header)*2)
+	for k, vs := range c.Request.Header {
+		header[textproto.CanonicalMIMEHeaderKey(k)] = vs
+		header[strings.ToLower(k)] = vs
+	}
+	return mapForm(c, v, header, "header")
+}
+
+func bindBody(c *gin.Context, v interface{}) error {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return nil
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, atomic.LoadInt64(&maxBodySize))
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType == binding.MIMEMultipartPOSTForm {
+		if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+			return bodyError(err)
+		}
+		return mapForm(c, v, c.Request.MultipartForm.Value, "form")
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return bodyError(err)
+	}
+	// 保留 body, 之后的 handler 仍可读取
+	c.Request.Body = io.NopCloser(bytes.NewReader(data))
+	if len(data) == 0 {
+		return nil
+	}
+
+	if mediaType == binding.MIMEPOSTForm {
+		values, err := url.ParseQuery(string(data))
+		if err != nil {
+			return ecode.InvalidParams
+		}
+		return mapForm(c, v, values, "form")
+	}
+
+	codec := codecForMediaType(mediaType)
+	if codec == nil {
+		return ecode.UnsupportedMediaType
+	}
+	if err = codec.Unmarshal(data, v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return typeError(c, []string{typeErr.Field})
+		}
+		return ecode.InvalidParams
+	}
+	return nil
+}
+
+// bodyError 超过 SetMaxBodySize 时返回 ecode.RequestTooLarge, 其他读取错误返回 ecode.InvalidParams
+func bodyError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return ecode.RequestTooLarge
+	}
+	return ecode.InvalidParams
+}
+
+// mapForm 将 values 按 tag 绑定到 v, 类型转换失败时返回附带 []FieldError 的 ecode.InvalidParams
+func mapForm(c *gin.Context, v interface{}, values map[string][]string, tag string) error {
+	if err := binding.MapFormWithTag(v, values, tag); err == nil {
+		return nil
+	}
+	// gin 返回的错误不包含字段名, 逐个 key 绑定到新的值找出转换失败的字段
+	t := reflect.TypeOf(v)
+	if t.Kind() != reflect.Ptr {
+		return ecode.InvalidParams
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var fields []string
+	for _, k := range keys {
+		if binding.MapFormWithTag(reflect.New(t.Elem()).Interface(), map[string][]string{k: values[k]}, tag) != nil {
+			fields = append(fields, k)
+		}
+	}
+	return typeError(c, fields)
+}
+
+// typeError 返回 fields 类型转换失败的 ecode.InvalidParams, 规则为 type
+func typeError(c *gin.Context, fields []string) error {
+	if len(fields) == 0 {
+		return ecode.InvalidParams
+	}
+	tmpls := templates(c.GetHeader("Accept-Language"))
+	tmpl, ok := tmpls["type"]
+	if !ok {
+		tmpl = tmpls[""]
+	}
+	details := make([]FieldError, 0, len(fields))
+	msgs := make([]string, 0, len(fields))
+	for _, field := range fields {
+		msg := strings.ReplaceAll(tmpl, "{field}", field)
+		details = append(details, FieldError{Field: field, Rule: "type", Message: msg})
+		msgs = append(msgs, msg)
+	}
+	return ecode.InvalidParams.SetMsg(strings.Join(msgs, "; ")).WithDetails(details)
+}
+
+// codecForMediaType 根据 media type 的子类型选择 Codec, 如 application/json 使用 json, application/problem+json 使用 json,
+// 没有 Content-Type 时使用 json
+func codecForMediaType(mediaType string) encoding.Codec {
+	if mediaType == "" {
+		return encoding.GetCodec("json")
+	}
+	i := strings.Index(mediaType, "/")
+	if i < 0 {
+		return nil
+	}
+	subtype := mediaType[i+1:]
+	if codec := encoding.GetCodec(subtype); codec != nil {
+		return codec
+	}
+	if j := strings.LastIndex(subtype, "+"); j >= 0 {
+		return encoding.GetCodec(subtype[j+1:])
+	}
+	return nil
+}
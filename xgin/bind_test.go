@@ -0,0 +1,124 @@
+package xgin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"skuld/ecode"
+)
+
+type bindReq struct {
+	ID    int    `uri:"id" binding:"required"`
+	Page  int    `form:"page" binding:"min=1"`
+	Token string `header:"X-Token" binding:"required"`
+	Name  string `json:"name" form:"name" binding:"required,max=5"`
+	Age   int    `json:"age" form:"age"`
+	User  struct {
+		Email string `json:"email" binding:"omitempty,email"`
+	} `json:"user"`
+}
+
+func TestBind(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetMaxBodySize(64)
+	defer SetMaxBodySize(10 << 20)
+
+	var got bindReq
+	e := gin.New()
+	e.POST("/user/:id", func(c *gin.Context) {
+		got = bindReq{}
+		if err := Bind(c, &got); err != nil {
+			Failure(c, err)
+			return
+		}
+		Success(c)
+	})
+
+	type testCase struct {
+		name     string
+		query    string
+		body     string
+		ctype    string
+		lang     string
+		code     int
+		fields   []string
+		messages string
+	}
+	testTable := []testCase{
+		{name: "ok", query: "page=2", body: `{"name":"tom"}`, ctype: "application/json", code: http.StatusOK},
+		{name: "form body", query: "page=2", body: `name=tom`, ctype: "application/x-www-form-urlencoded", code: http.StatusOK},
+		{
+			name: "invalid", query: "page=0", body: `{"name":"tommy jones","user":{"email":"x"}}`, ctype: "application/json",
+			code: 900400, fields: []string{"page", "name", "user.email"},
+			messages: "page不能小于1; name不能大于5; user.email必须是有效的邮箱地址",
+		},
+		{
+			name: "english", query: "page=2", body: `{}`, ctype: "application/json", lang: "en-US,en;q=0.9",
+			code: 900400, fields: []string{"name"}, messages: "name is required",
+		},
+		{name: "unsupported media type", query: "page=2", body: `name`, ctype: "application/octet-stream", code: 900415},
+		{
+			name: "query type", query: "page=x", body: `{"name":"tom"}`, ctype: "application/json",
+			code: 900400, fields: []string{"page"}, messages: "page类型错误",
+		},
+		{
+			name: "json type", query: "page=2", body: `{"name":"tom","age":"18"}`, ctype: "application/json",
+			code: 900400, fields: []string{"age"}, messages: "age类型错误",
+		},
+		{
+			name: "form type", query: "page=2", body: `name=tom&age=x`, ctype: "application/x-www-form-urlencoded",
+			code: 900400, fields: []string{"age"}, messages: "age类型错误",
+		},
+		{name: "too large", query: "page=2", body: `{"name":"` + strings.Repeat("a", 64) + `"}`, ctype: "application/json", code: 900413},
+	}
+
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/user/7?"+v.query, strings.NewReader(v.body))
+			req.Header.Set("Content-Type", v.ctype)
+			req.Header.Set("X-Token", "secret")
+			if v.lang != "" {
+				req.Header.Set("Accept-Language", v.lang)
+			}
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, req)
+
+			var resp struct {
+				Code int          `json:"code"`
+				Msg  string       `json:"msg"`
+				Data []FieldError `json:"data"`
+			}
+			if v.code != http.StatusOK {
+				_ = json.Unmarshal(w.Body.Bytes(), &resp)
+			} else {
+				resp.Code = w.Code
+			}
+			if resp.Code != v.code {
+				t.Fatalf("expect code %d, but get %d: %s", v.code, resp.Code, w.Body.String())
+			}
+			if v.code == http.StatusOK && (got.ID != 7 || got.Page != 2 || got.Token != "secret" || got.Name != "tom") {
+				t.Errorf("unexpected bind result: %+v", got)
+			}
+			if v.messages != "" && resp.Msg != v.messages {
+				t.Errorf("expect msg %q, but get %q", v.messages, resp.Msg)
+			}
+			if len(resp.Data) != len(v.fields) {
+				t.Fatalf("expect fields %v, but get %+v", v.fields, resp.Data)
+			}
+			for i, f := range v.fields {
+				if resp.Data[i].Field != f {
+					t.Errorf("expect field %s, but get %s", f, resp.Data[i].Field)
+				}
+			}
+		})
+	}
+
+	if ecode.Details(ecode.InvalidParams) != nil {
+		t.Errorf("WithDetails should not modify the registered error")
+	}
+}
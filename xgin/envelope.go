@@ -0,0 +1,184 @@
+package xgin
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+
+	"skuld/requestid"
+)
+
+// Envelope 响应格式, 通过 SetEnvelope 设置全局默认值, 或通过 UseEnvelope 中间件为路由组单独设置;
+// 修改时以 DefaultEnvelope() 为基础, 字段名为空时使用默认字段名
+type Envelope struct {
+	// CodeField / DataField / MsgField 错误码, 数据和信息的字段名, 默认 code / data / msg
+	CodeField string
+	DataField string
+	MsgField  string
+	// SuccessCode / SuccessMsg Success 返回的错误码和信息, 默认 200 / OK
+	SuccessCode int
+	SuccessMsg  string
+	// RequestIDField 不为空时以该字段名返回请求 ID
+	RequestIDField string
+	// TimestampField 不为空时以该字段名返回 unix 时间戳 (秒)
+	TimestampField string
+	// Extra 返回附加到每个响应的其他字段
+	Extra func(c *gin.Context) map[string]interface{}
+
+	// Problem 为 true 时 Failure 以 RFC 7807 application/problem+json 格式返回错误,
+	// 未注册 http 状态码且不在 100200 ~ 100600 中的 ecode 错误返回 400, 其他错误返回 500
+	Problem bool
+	// ProblemType 返回错误码对应的 problem type URI, 默认 about:blank
+	ProblemType func(code int) string
+}
+
+// DefaultEnvelope 返回默认的响应格式 {"code": 200, "data": {}, "msg": "OK"}
+func DefaultEnvelope() Envelope {
+	return Envelope{
+		CodeField:   "code",
+		DataField:   "data",
+		MsgField:    "msg",
+		SuccessCode: 200,
+		SuccessMsg:  "OK",
+	}
+}
+
+func (e Envelope) normalize() *Envelope {
+	d := DefaultEnvelope()
+	if e.CodeField == "" {
+		e.CodeField = d.CodeField
+	}
+	if e.DataField == "" {
+		e.DataField = d.DataField
+	}
+	if e.MsgField == "" {
+		e.MsgField = d.MsgField
+	}
+	return &e
+}
+
+const envelopeKey = "skuld/xgin/envelope"
+
+var (
+	envelopeMu      sync.RWMutex
+	defaultEnvelope = DefaultEnvelope().normalize()
+)
+
+// SetEnvelope 设置全局默认的响应格式
+func SetEnvelope(e Envelope) {
+	envelopeMu.Lock()
+	defer envelopeMu.Unlock()
+	defaultEnvelope = e.normalize()
+}
+
+// UseEnvelope 返回为之后的 handler 设置响应格式的中间件, 如对外的路由组使用 Problem 模式
+func UseEnvelope(e Envelope) gin.HandlerFunc {
+	env := e.normalize()
+	return func(c *gin.Context) {
+		c.Set(envelopeKey, env)
+	}
+}
+
+func envelopeOf(c *gin.Context) *Envelope {
+	if v, ok := c.Get(envelopeKey); ok {
+		if env, ok := v.(*Envelope); ok {
+			return env
+		}
+	}
+	envelopeMu.RLock()
+	defer envelopeMu.RUnlock()
+	return defaultEnvelope
+}
+
+// extra 返回请求 ID, 时间戳以及 Extra 中的字段, Extra 中的字段按 key 排序
+func (e *Envelope) extra(c *gin.Context) body {
+	var b body
+	if e.RequestIDField != "" {
+		b = append(b, field{key: e.RequestIDField, value: requestid.FromContext(c.Request.Context())})
+	}
+	if e.TimestampField != "" {
+		b = append(b, field{key: e.TimestampField, value: time.Now().Unix()})
+	}
+	if e.Extra != nil {
+		kvs := e.Extra(c)
+		keys := make([]string, 0, len(kvs))
+		for k := range kvs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b = append(b, field{key: k, value: kvs[k]})
+		}
+	}
+	return b
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+// body 按顺序输出字段的响应体, 支持 json / xml / yaml
+type body []field
+
+func (b body) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range b {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MarshalXML 以 response 为根节点, 值为 nil 的字段不输出
+func (b body) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Local: "response"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, f := range b {
+		if f.value == nil {
+			continue
+		}
+		if err := e.EncodeElement(f.value, xml.StartElement{Name: xml.Name{Local: f.key}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (b body) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range b {
+		key := &yaml.Node{}
+		if err := key.Encode(f.key); err != nil {
+			return nil, err
+		}
+		value := &yaml.Node{}
+		if err := value.Encode(f.value); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, key, value)
+	}
+	return node, nil
+}
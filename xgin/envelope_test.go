@@ -0,0 +1,83 @@
+package xgin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"skuld/ecode"
+)
+
+// 错误码只能定义一次, 在包级别定义以支持 go test -count
+var (
+	notFound      = ecode.New(200404, "不存在")
+	legacyTooMany = ecode.New(100429, "请求过多")
+	unregistered  = ecode.New(200001, "未注册")
+)
+
+func TestEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	RegisterStatus(notFound, http.StatusNotFound)
+
+	e := gin.New()
+	custom := e.Group("/custom", UseEnvelope(Envelope{
+		CodeField:   "errno",
+		DataField:   "result",
+		MsgField:    "message",
+		SuccessCode: 0,
+		SuccessMsg:  "success",
+		Extra: func(c *gin.Context) map[string]interface{} {
+			return map[string]interface{}{"version": "v1"}
+		},
+	}))
+	custom.GET("/ok", func(c *gin.Context) { Success(c, 1) })
+	problem := e.Group("/problem", UseEnvelope(Envelope{Problem: true}))
+	problem.GET("/not-found", func(c *gin.Context) { Failure(c, notFound) })
+	problem.GET("/invalid", func(c *gin.Context) {
+		Failure(c, ecode.InvalidParams.WithDetails([]FieldError{{Field: "name", Rule: "required"}}))
+	})
+	e.GET("/not-found", func(c *gin.Context) { Failure(c, notFound) })
+	e.GET("/unregistered", func(c *gin.Context) { Failure(c, unregistered) })
+	e.GET("/legacy", func(c *gin.Context) { Failure(c, legacyTooMany) })
+
+	type testCase struct {
+		name        string
+		path        string
+		status      int
+		contentType string
+		body        string
+	}
+	testTable := []testCase{
+		{name: "custom fields", path: "/custom/ok", status: http.StatusOK, contentType: "application/json; charset=utf-8",
+			body: `{"errno":0,"result":1,"message":"success","version":"v1"}`},
+		{name: "registered status", path: "/not-found", status: http.StatusNotFound, contentType: "application/json; charset=utf-8",
+			body: `{"code":200404,"data":{},"msg":"不存在"}`},
+		{name: "unregistered status", path: "/unregistered", status: http.StatusOK, contentType: "application/json; charset=utf-8",
+			body: `{"code":200001,"data":{},"msg":"未注册"}`},
+		{name: "legacy range", path: "/legacy", status: http.StatusTooManyRequests, contentType: "application/json; charset=utf-8",
+			body: `{"code":100429,"data":{},"msg":"请求过多"}`},
+		{name: "problem", path: "/problem/not-found", status: http.StatusNotFound, contentType: "application/problem+json; charset=utf-8",
+			body: `{"type":"about:blank","title":"Not Found","status":404,"detail":"不存在","instance":"/problem/not-found","code":200404}`},
+		{name: "problem details", path: "/problem/invalid", status: http.StatusBadRequest, contentType: "application/problem+json; charset=utf-8",
+			body: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"参数错误","instance":"/problem/invalid","code":900400,"errors":[{"field":"name","rule":"required","message":""}]}`},
+	}
+
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, v.path, nil))
+
+			if w.Code != v.status {
+				t.Errorf("expect status %d, but get %d", v.status, w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != v.contentType {
+				t.Errorf("expect content type %q, but get %q", v.contentType, got)
+			}
+			if got := w.Body.String(); got != v.body {
+				t.Errorf("expect body %s, but get %s", v.body, got)
+			}
+		})
+	}
+}
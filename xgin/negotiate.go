@@ -0,0 +1,127 @@
+package xgin
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"skuld/encoding"
+)
+
+type mediaType struct {
+	name  string
+	codec string
+}
+
+var (
+	mediaTypesMu sync.RWMutex
+	// mediaTypes 响应支持的 media type, 第一个为 Accept 为空或 */* 时的默认值;
+	// 默认只返回 json, 避免浏览器的 Accept (application/xml;q=0.9,*/*;q=0.8) 选中 xml
+	mediaTypes = []mediaType{
+		{name: "application/json", codec: "json"},
+	}
+)
+
+// RegisterMediaType 注册响应的 media type, 请求的 Accept 匹配时使用 encoding 中名称为 codec 的 Codec 编码响应,
+// 重复注册时覆盖之前的 codec; 默认只支持 application/json, 需要返回 xml / yaml 的服务自行注册:
+//
+//	xgin.RegisterMediaType("application/xml", "xml")
+//	xgin.RegisterMediaType("application/yaml", "yaml")
+func RegisterMediaType(name, codec string) {
+	mediaTypesMu.Lock()
+	defer mediaTypesMu.Unlock()
+
+	name = strings.ToLower(name)
+	for i, mt := range mediaTypes {
+		if mt.name == name {
+			mediaTypes[i].codec = codec
+			return
+		}
+	}
+	mediaTypes = append(mediaTypes, mediaType{name: name, codec: codec})
+}
+
+type acceptRange struct {
+	name string
+	q    float64
+}
+
+// parseAccept 按 q 值从高到低返回 Accept 中的 media range, q 为 0 的 range 被忽略
+func parseAccept(accept string) []acceptRange {
+	ranges := make([]acceptRange, 0, 4)
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if ok && strings.EqualFold(k, "q") {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, acceptRange{name: name, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	return ranges
+}
+
+// negotiate 根据 Accept 选择响应的 media type 和 Codec, 没有匹配的 media type 时使用 json;
+// q 值相同的 range 中有 range 匹配默认的 media type (如 */*) 时优先使用默认的 media type
+func negotiate(accept string) (string, encoding.Codec) {
+	mediaTypesMu.RLock()
+	defer mediaTypesMu.RUnlock()
+
+	ranges := parseAccept(accept)
+	for i := 0; i < len(ranges); {
+		j := i
+		for j < len(ranges) && ranges[j].q == ranges[i].q {
+			j++
+		}
+		var (
+			name  string
+			codec encoding.Codec
+		)
+		for _, r := range ranges[i:j] {
+			for k, mt := range mediaTypes {
+				if !matchMediaRange(r.name, mt.name) {
+					continue
+				}
+				c := encoding.GetCodec(mt.codec)
+				if c == nil {
+					continue
+				}
+				if k == 0 {
+					return mt.name, c
+				}
+				if codec == nil {
+					name, codec = mt.name, c
+				}
+			}
+		}
+		if codec != nil {
+			return name, codec
+		}
+		i = j
+	}
+	return "application/json", encoding.GetCodec("json")
+}
+
+func matchMediaRange(r, name string) bool {
+	if r == "*/*" || r == name {
+		return true
+	}
+	if strings.HasSuffix(r, "/*") {
+		return strings.HasPrefix(name, strings.TrimSuffix(r, "*"))
+	}
+	return false
+}
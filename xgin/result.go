@@ -1,8 +1,9 @@
 package xgin
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"sync"
 
 	"github.com/gin-gonic/gin"
 
@@ -11,71 +12,117 @@ import (
 
 var emptyData = make(map[string]interface{})
 
-type response struct {
-	Code int         `json:"code"`
-	Data interface{} `json:"data"`
-	Msg  string      `json:"msg"`
-}
-
-func (r *response) reset() {
-	r.Code = 0
-	r.Data = nil
-	r.Msg = ""
-}
-
-var (
-	resppool = sync.Pool{New: func() interface{} {
-		return &response{
-			Code: 0,
-			Data: nil,
-			Msg:  "",
-		}
-	}}
-)
-
+// Success 以 Envelope 中的 SuccessCode 和 SuccessMsg 返回 data
 func Success(c *gin.Context, datas ...interface{}) {
 	var data interface{}
 	if len(datas) > 0 {
 		data = datas[0]
 	}
-	result(c, http.StatusOK, "OK", data)
+	env := envelopeOf(c)
+	result(c, env, http.StatusOK, env.SuccessCode, env.SuccessMsg, data)
 }
 
+// Failure 返回 err 对应的错误码和错误信息, err 通过 ecode.E.WithDetails 附带了详细信息时将其作为 data 返回;
+// http 状态码通过 RegisterStatus 注册, 未注册时 100200 ~ 100600 映射为对应的 http 状态码, 其他返回 200; 请求超时导致的 context.DeadlineExceeded 按 ecode.Timeout 返回
 func Failure(c *gin.Context, err error) {
-	c.Abort()
-	errCode := c.GetInt("ErrorCode")
-	code, msg := ecode.Info(err)
-	if errCode > 0 {
-		code = errCode
-	}
-	result(c, code, msg, emptyData)
+	FailureData(c, err, ecode.Details(err))
 }
 
 func FailureData(c *gin.Context, err error, data interface{}) {
 	c.Abort()
+	if _, ok := err.(*ecode.E); !ok && errors.Is(err, context.DeadlineExceeded) {
+		err = ecode.Timeout
+	}
 	errCode := c.GetInt("ErrorCode")
 	code, msg := ecode.Info(err)
 	if errCode > 0 {
 		code = errCode
 	}
-	result(c, code, msg, data)
+	env := envelopeOf(c)
+	if env.Problem {
+		problem(c, env, err, code, msg, data)
+		return
+	}
+	result(c, env, httpStatus(code, http.StatusOK), code, msg, data)
 }
 
-func result(c *gin.Context, code int, msg string, data interface{}) {
-	httpCode := http.StatusOK
-	if code > 100200 && code <= 100600 {
-		httpCode = code - 100000
-	}
+func result(c *gin.Context, env *Envelope, httpCode int, code int, msg string, data interface{}) {
 	if data == nil {
 		data = emptyData
 	}
-	resp := resppool.Get().(*response)
-	resp.Code = code
-	resp.Data = data
-	resp.Msg = msg
+	b := make(body, 0, 5)
+	b = append(b,
+		field{key: env.CodeField, value: code},
+		field{key: env.DataField, value: data},
+		field{key: env.MsgField, value: msg},
+	)
+	b = append(b, env.extra(c)...)
+	render(c, httpCode, b, 1)
+}
+
+// problem 以 RFC 7807 格式返回错误, 错误码和详细信息通过扩展字段 code 和 errors 返回
+func problem(c *gin.Context, env *Envelope, err error, code int, msg string, data interface{}) {
+	def := http.StatusBadRequest
+	if _, ok := err.(*ecode.E); !ok {
+		def = http.StatusInternalServerError
+	}
+	status := httpStatus(code, def)
+	typ := "about:blank"
+	if env.ProblemType != nil {
+		if t := env.ProblemType(code); t != "" {
+			typ = t
+		}
+	}
+	b := make(body, 0, 8)
+	b = append(b,
+		field{key: "type", value: typ},
+		field{key: "title", value: http.StatusText(status)},
+		field{key: "status", value: status},
+		field{key: "detail", value: msg},
+		field{key: "instance", value: c.Request.URL.Path},
+		field{key: "code", value: code},
+	)
+	if data != nil {
+		b = append(b, field{key: "errors", value: data})
+	}
+	b = append(b, env.extra(c)...)
+	c.Render(status, problemJSON{b})
+}
+
+type problemJSON struct {
+	body body
+}
+
+func (r problemJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	data, err := r.body.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
 
-	c.JSON(httpCode, resp)
+func (problemJSON) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+}
 
-	resp.reset()
-	resppool.Put(resp)
+// render 根据 Accept 选择编码方式写出响应, 编码失败时 (如 xml 不支持 map) 退回 json, dataIdx 为 b 中 data 字段的下标
+func render(c *gin.Context, httpCode int, b body, dataIdx int) {
+	// 追加而不是覆盖, 保留 cors 等中间件设置的 Vary
+	c.Writer.Header().Add("Vary", "Accept")
+	name, codec := negotiate(c.GetHeader("Accept"))
+	if name != "application/json" {
+		data := b[dataIdx].value
+		// emptyData 只用于在 json 中输出 {}, 其他格式不输出 data
+		if m, ok := data.(map[string]interface{}); ok && len(m) == 0 && codec.Name() != "json" {
+			b[dataIdx].value = nil
+		}
+		if out, err := codec.Marshal(b); err == nil {
+			c.Data(httpCode, name+"; charset=utf-8", out)
+			return
+		}
+		b[dataIdx].value = data
+	}
+	c.JSON(httpCode, b)
 }
@@ -0,0 +1,92 @@
+package xgin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNegotiate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	RegisterMediaType("application/vnd.skuld+json", "json")
+	RegisterMediaType("application/xml", "xml")
+	RegisterMediaType("application/yaml", "yaml")
+
+	e := gin.New()
+	e.GET("/", func(c *gin.Context) {
+		Success(c, map[string]string{"name": "tom"})
+	})
+	e.GET("/empty", func(c *gin.Context) {
+		Success(c)
+	})
+
+	type testCase struct {
+		name        string
+		path        string
+		accept      string
+		contentType string
+		body        string
+	}
+	testTable := []testCase{
+		{name: "default", path: "/", contentType: "application/json; charset=utf-8", body: `"name":"tom"`},
+		{name: "unknown", path: "/", accept: "text/html", contentType: "application/json; charset=utf-8", body: `"name":"tom"`},
+		{name: "xml", path: "/empty", accept: "application/xml", contentType: "application/xml; charset=utf-8", body: "<response><code>200</code><msg>OK</msg></response>"},
+		{name: "q value", path: "/", accept: "application/xml;q=0.5, application/yaml", contentType: "application/yaml; charset=utf-8", body: "name: tom"},
+		{name: "xml map falls back to json", path: "/", accept: "application/xml", contentType: "application/json; charset=utf-8", body: `"name":"tom"`},
+		{name: "tie prefers json", path: "/empty", accept: "application/xml, */*", contentType: "application/json; charset=utf-8", body: `"code":200`},
+		{name: "registered", path: "/", accept: "application/vnd.skuld+json", contentType: "application/vnd.skuld+json; charset=utf-8", body: `"name":"tom"`},
+	}
+
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, v.path, nil)
+			if v.accept != "" {
+				req.Header.Set("Accept", v.accept)
+			}
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Content-Type"); got != v.contentType {
+				t.Errorf("expect content type %q, but get %q", v.contentType, got)
+			}
+			if !strings.Contains(w.Body.String(), v.body) {
+				t.Errorf("expect body contains %q, but get %q", v.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestNegotiateDefault(t *testing.T) {
+	mediaTypesMu.Lock()
+	registered := mediaTypes
+	mediaTypes = []mediaType{{name: "application/json", codec: "json"}}
+	mediaTypesMu.Unlock()
+	defer func() {
+		mediaTypesMu.Lock()
+		mediaTypes = registered
+		mediaTypesMu.Unlock()
+	}()
+
+	// 默认不返回 xml, 浏览器的 Accept 仍然得到 json
+	if name, _ := negotiate("text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"); name != "application/json" {
+		t.Errorf("expect application/json, but get %s", name)
+	}
+}
+
+func TestRenderKeepsVary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/", func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Origin")
+		Success(c)
+	})
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := strings.Join(w.Header().Values("Vary"), ","); got != "Origin,Accept" {
+		t.Errorf("expect Vary Origin,Accept, but get %q", got)
+	}
+}
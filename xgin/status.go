@@ -0,0 +1,51 @@
+package xgin
+
+import (
+	"net/http"
+	"sync"
+
+	"skuld/ecode"
+)
+
+var (
+	statusMu sync.RWMutex
+	// statuses 错误码 -> http 状态码, 未注册的错误码返回 200
+	statuses = map[int]int{
+		ecode.Code(ecode.InvalidParams):         http.StatusBadRequest,
+		ecode.Code(ecode.UnsupportedMediaType):  http.StatusUnsupportedMediaType,
+		ecode.Code(ecode.RequestTooLarge):       http.StatusRequestEntityTooLarge,
+		ecode.Code(ecode.Unauthorized):          http.StatusUnauthorized,
+		ecode.Code(ecode.Forbidden):             http.StatusForbidden,
+		ecode.Code(ecode.IdempotencyInProgress): http.StatusConflict,
+		ecode.Code(ecode.IdempotencyKeyReused):  http.StatusUnprocessableEntity,
+		ecode.Code(ecode.ServerPanic):           http.StatusInternalServerError,
+		ecode.Code(ecode.Timeout):               http.StatusGatewayTimeout,
+	}
+)
+
+// RegisterStatus 注册 err 对应的错误码返回的 http 状态码, 重复注册时覆盖, 一般在定义错误码时调用:
+//
+//	var UserNotFound = ecode.New(200404, "用户不存在")
+//
+//	func init() {
+//		xgin.RegisterStatus(UserNotFound, http.StatusNotFound)
+//	}
+func RegisterStatus(err *ecode.E, status int) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	statuses[ecode.Code(err)] = status
+}
+
+// httpStatus 返回错误码对应的 http 状态码, 未注册时兼容之前的规则, 100200 < code <= 100600 映射为 code - 100000,
+// 其他返回 def
+func httpStatus(code int, def int) int {
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	if status, ok := statuses[code]; ok {
+		return status
+	}
+	if code > 100200 && code <= 100600 {
+		return code - 100000
+	}
+	return def
+}
@@ -0,0 +1,183 @@
+package xgin
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"skuld/ecode"
+)
+
+// FieldError 单个字段的校验错误, 作为 ecode.InvalidParams 的详细信息返回给客户端
+type FieldError struct {
+	// Field 字段名, 取 json / form / uri / header tag 中的第一个, 嵌套字段以 "." 连接
+	Field string `json:"field"`
+	// Rule 未通过的校验规则, 如 required, max
+	Rule string `json:"rule"`
+	// Param 校验规则的参数, 如 max=20 中的 20
+	Param string `json:"param,omitempty"`
+	// Message 根据请求的 Accept-Language 翻译后的错误信息
+	Message string `json:"message"`
+}
+
+// DefaultLanguage 请求未指定或指定了未注册的语言时使用的语言
+const DefaultLanguage = "zh"
+
+var (
+	validate = newValidate()
+
+	messagesMu sync.RWMutex
+	// messages 语言 -> 校验规则 -> 错误信息模板, 模板中的 {field} 和 {param} 分别替换为字段名和规则参数
+	messages = map[string]map[string]string{
+		"zh": {
+			"":         "{field}校验失败",
+			"required": "{field}为必填字段",
+			"len":      "{field}长度必须为{param}",
+			"min":      "{field}不能小于{param}",
+			"max":      "{field}不能大于{param}",
+			"eq":       "{field}必须等于{param}",
+			"ne":       "{field}不能等于{param}",
+			"gt":       "{field}必须大于{param}",
+			"gte":      "{field}必须大于或等于{param}",
+			"lt":       "{field}必须小于{param}",
+			"lte":      "{field}必须小于或等于{param}",
+			"oneof":    "{field}必须是[{param}]中的一个",
+			"email":    "{field}必须是有效的邮箱地址",
+			"url":      "{field}必须是有效的 URL",
+			"uuid":     "{field}必须是有效的 UUID",
+			"numeric":  "{field}必须是数字",
+			"alphanum": "{field}只能包含字母和数字",
+			"datetime": "{field}必须符合格式{param}",
+			"type":     "{field}类型错误",
+		},
+		"en": {
+			"":         "{field} is invalid",
+			"required": "{field} is required",
+			"len":      "{field} must be {param} in length",
+			"min":      "{field} must be at least {param}",
+			"max":      "{field} must be at most {param}",
+			"eq":       "{field} must be equal to {param}",
+			"ne":       "{field} must not be equal to {param}",
+			"gt":       "{field} must be greater than {param}",
+			"gte":      "{field} must be greater than or equal to {param}",
+			"lt":       "{field} must be less than {param}",
+			"lte":      "{field} must be less than or equal to {param}",
+			"oneof":    "{field} must be one of [{param}]",
+			"email":    "{field} must be a valid email address",
+			"url":      "{field} must be a valid URL",
+			"uuid":     "{field} must be a valid UUID",
+			"numeric":  "{field} must be numeric",
+			"alphanum": "{field} can only contain letters and numbers",
+			"datetime": "{field} must match the format {param}",
+			"type":     "{field} has an invalid type",
+		},
+	}
+)
+
+func newValidate() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "uri", "header"} {
+			name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				continue
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return f.Name
+	})
+	return v
+}
+
+// Validator 返回 Bind 使用的校验器, 可以注册自定义的校验规则, 规则的错误信息通过 RegisterMessages 注册
+func Validator() *validator.Validate {
+	return validate
+}
+
+// RegisterMessages 注册或覆盖 lang 语言下校验规则的错误信息模板, key 为规则名, 空字符串表示未注册规则的默认信息
+func RegisterMessages(lang string, msgs map[string]string) {
+	messagesMu.Lock()
+	defer messagesMu.Unlock()
+
+	// 写时复制, templates 返回的 map 在释放锁后仍会被读取, 不能原地修改
+	lang = strings.ToLower(lang)
+	prev := messages[lang]
+	m := make(map[string]string, len(prev)+len(msgs))
+	for rule, msg := range prev {
+		m[rule] = msg
+	}
+	for rule, msg := range msgs {
+		m[rule] = msg
+	}
+	messages[lang] = m
+}
+
+// Validate 根据 binding tag 校验 v, 失败时返回附带 []FieldError 的 ecode.InvalidParams, 错误信息为所有字段的信息以 "; " 连接
+func Validate(c *gin.Context, v interface{}) error {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		// 非结构体等无法校验的值不视为参数错误
+		var invalid *validator.InvalidValidationError
+		if errors.As(err, &invalid) {
+			return nil
+		}
+		return ecode.InvalidParams
+	}
+
+	tmpls := templates(c.GetHeader("Accept-Language"))
+	fields := make([]FieldError, 0, len(errs))
+	msgs := make([]string, 0, len(errs))
+	for _, fe := range errs {
+		field := fieldName(fe)
+		tmpl, ok := tmpls[fe.Tag()]
+		if !ok {
+			tmpl = tmpls[""]
+		}
+		msg := strings.NewReplacer("{field}", field, "{param}", fe.Param()).Replace(tmpl)
+		fields = append(fields, FieldError{Field: field, Rule: fe.Tag(), Param: fe.Param(), Message: msg})
+		msgs = append(msgs, msg)
+	}
+	return ecode.InvalidParams.SetMsg(strings.Join(msgs, "; ")).WithDetails(fields)
+}
+
+// fieldName 去掉 Namespace 中的顶层结构体名, 如 Req.user.name -> user.name
+func fieldName(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+// templates 根据 Accept-Language 依次尝试完整语言标签和主语言, 如 en-US -> en-us, en; 返回的 map 只读
+func templates(acceptLanguage string) map[string]string {
+	messagesMu.RLock()
+	defer messagesMu.RUnlock()
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		lang := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		if lang == "" || lang == "*" {
+			continue
+		}
+		if m, ok := messages[lang]; ok {
+			return m
+		}
+		if i := strings.Index(lang, "-"); i > 0 {
+			if m, ok := messages[lang[:i]]; ok {
+				return m
+			}
+		}
+	}
+	return messages[DefaultLanguage]
+}
@@ -0,0 +1,39 @@
+package xgin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegisterMessagesConcurrent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	type req struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			RegisterMessages("en", map[string]string{"concurrent_test": "{field} is invalid"})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+			c.Request.Header.Set("Accept-Language", "en")
+			if err := Validate(c, &req{}); err == nil || err.Error() != "name is required" {
+				t.Errorf("expect name is required, but get %v", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
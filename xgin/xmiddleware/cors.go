@@ -2,16 +2,195 @@ package xmiddleware
 
 import (
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Cors(c *gin.Context) {
-	c.Writer.Header().Add("Access-Control-Allow-Origin", "*")
-	c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-	c.Writer.Header().Set("Access-Control-Allow-Headers", "*")
-	c.Writer.Header().Set("Access-Control-Allow-Methods", "*")
-	if c.Request.Method == http.MethodOptions {
-		c.AbortWithStatus(200)
+type CorsOption func(*corsOptions)
+
+type corsOptions struct {
+	allowAll      bool
+	origins       map[string]struct{}
+	wildcards     [][2]string
+	regexps       []*regexp.Regexp
+	methods       []string
+	headers       []string
+	exposeHeaders []string
+	maxAge        time.Duration
+	credentials   bool
+}
+
+// WithCorsOrigins 设置允许的 Origin, 支持完整匹配 (https://a.com), 子域名通配 (https://*.a.com) 以及 * 表示允许所有, 默认 *
+func WithCorsOrigins(origins ...string) CorsOption {
+	return func(o *corsOptions) {
+		o.allowAll = false
+		o.origins = make(map[string]struct{})
+		o.wildcards = nil
+		for _, origin := range origins {
+			origin = strings.ToLower(origin)
+			switch {
+			case origin == "*":
+				o.allowAll = true
+			case strings.Contains(origin, "*"):
+				i := strings.Index(origin, "*")
+				o.wildcards = append(o.wildcards, [2]string{origin[:i], origin[i+1:]})
+			default:
+				o.origins[origin] = struct{}{}
+			}
+		}
+	}
+}
+
+// WithCorsOriginRegexps 设置允许的 Origin 正则, 与 WithCorsOrigins 任一匹配即允许
+func WithCorsOriginRegexps(res ...*regexp.Regexp) CorsOption {
+	return func(o *corsOptions) {
+		o.regexps = res
+	}
+}
+
+// WithCorsMethods 设置预检请求允许的方法, 默认 GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS
+func WithCorsMethods(methods ...string) CorsOption {
+	return func(o *corsOptions) {
+		o.methods = methods
+	}
+}
+
+// WithCorsHeaders 设置预检请求允许的请求头, 为空或包含 * 时允许预检请求中声明的所有请求头
+func WithCorsHeaders(headers ...string) CorsOption {
+	return func(o *corsOptions) {
+		o.headers = headers
+	}
+}
+
+// WithCorsExposeHeaders 设置浏览器允许读取的响应头, 如 X-Request-Id
+func WithCorsExposeHeaders(headers ...string) CorsOption {
+	return func(o *corsOptions) {
+		o.exposeHeaders = headers
+	}
+}
+
+// WithCorsMaxAge 设置预检请求结果的缓存时间, 0 表示不设置
+func WithCorsMaxAge(d time.Duration) CorsOption {
+	return func(o *corsOptions) {
+		o.maxAge = d
+	}
+}
+
+// WithCorsCredentials 设置是否允许携带 cookie 等凭证, 允许时响应回显请求的 Origin 而不是 *;
+// 必须同时通过 WithCorsOrigins / WithCorsOriginRegexps 设置明确的 Origin 列表, 不能包含 *
+func WithCorsCredentials(credentials bool) CorsOption {
+	return func(o *corsOptions) {
+		o.credentials = credentials
+	}
+}
+
+func (o *corsOptions) allowed(origin string) bool {
+	if o.allowAll {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if _, ok := o.origins[lower]; ok {
+		return true
+	}
+	for _, w := range o.wildcards {
+		if len(lower) > len(w[0])+len(w[1]) && strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+			return true
+		}
+	}
+	for _, re := range o.regexps {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewCors 根据 opts 处理跨域请求, Origin 不在允许列表时不设置跨域响应头, 预检请求返回 403;
+// 允许的预检请求直接返回 204, 不再执行后续 handler.
+// 允许凭证时允许所有 Origin 会让任意网站读取携带 cookie 的响应, 此时 panic
+func NewCors(opts ...CorsOption) gin.HandlerFunc {
+	options := corsOptions{
+		allowAll: true,
+		methods: []string{
+			http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+			http.MethodDelete, http.MethodHead, http.MethodOptions,
+		},
+	}
+	for _, option := range opts {
+		option(&options)
+	}
+	if options.credentials && options.allowAll {
+		panic("xmiddleware: cors credentials require an explicit origin allow-list")
+	}
+
+	methods := strings.Join(options.methods, ", ")
+	headers := strings.Join(options.headers, ", ")
+	for _, h := range options.headers {
+		if h == "*" {
+			headers = ""
+		}
 	}
+	exposeHeaders := strings.Join(options.exposeHeaders, ", ")
+	maxAge := ""
+	if options.maxAge > 0 {
+		maxAge = strconv.Itoa(int(options.maxAge / time.Second))
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		header := c.Writer.Header()
+		// 响应内容随 Origin 变化, 防止缓存将一个 Origin 的响应返回给另一个 Origin
+		header.Add("Vary", "Origin")
+		if origin == "" {
+			return
+		}
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if !options.allowed(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+			}
+			return
+		}
+
+		if options.allowAll {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if options.credentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", methods)
+		if headers != "" {
+			header.Set("Access-Control-Allow-Headers", headers)
+		} else if requested := c.GetHeader("Access-Control-Request-Headers"); requested != "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		}
+		if maxAge != "" {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+var defaultCors = NewCors()
+
+// Cors 允许所有 Origin 不携带凭证的跨域请求, 需要携带凭证时使用 NewCors 设置明确的 Origin 列表
+func Cors(c *gin.Context) {
+	defaultCors(c)
 }
@@ -0,0 +1,101 @@
+package xmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	e := gin.New()
+	e.Use(NewCors(
+		WithCorsOrigins("https://a.com", "https://*.b.com"),
+		WithCorsOriginRegexps(regexp.MustCompile(`^http://localhost:\d+$`)),
+		WithCorsHeaders("Content-Type", "Authorization"),
+		WithCorsExposeHeaders("X-Request-Id"),
+		WithCorsMaxAge(time.Hour),
+		WithCorsCredentials(true),
+	))
+	e.GET("/", func(c *gin.Context) {})
+
+	type testCase struct {
+		name      string
+		method    string
+		origin    string
+		status    int
+		allowed   string
+		preflight bool
+	}
+	testTable := []testCase{
+		{name: "exact", method: http.MethodGet, origin: "https://a.com", status: http.StatusOK, allowed: "https://a.com"},
+		{name: "wildcard subdomain", method: http.MethodGet, origin: "https://x.b.com", status: http.StatusOK, allowed: "https://x.b.com"},
+		{name: "wildcard requires subdomain", method: http.MethodGet, origin: "https://b.com", status: http.StatusOK},
+		{name: "regexp", method: http.MethodGet, origin: "http://localhost:8080", status: http.StatusOK, allowed: "http://localhost:8080"},
+		{name: "not allowed", method: http.MethodGet, origin: "https://c.com", status: http.StatusOK},
+		{name: "preflight", method: http.MethodOptions, origin: "https://a.com", status: http.StatusNoContent, allowed: "https://a.com", preflight: true},
+		{name: "preflight not allowed", method: http.MethodOptions, origin: "https://c.com", status: http.StatusForbidden, preflight: true},
+	}
+
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			req := httptest.NewRequest(v.method, "/", nil)
+			req.Header.Set("Origin", v.origin)
+			if v.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, req)
+
+			if w.Code != v.status {
+				t.Errorf("expect status %d, but get %d", v.status, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != v.allowed {
+				t.Errorf("expect allow origin %q, but get %q", v.allowed, got)
+			}
+			if w.Header().Get("Vary") != "Origin" {
+				t.Errorf("expect Vary: Origin, but get %v", w.Header().Values("Vary"))
+			}
+			if v.allowed != "" && w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+				t.Errorf("expect credentials allowed")
+			}
+			if v.preflight && v.allowed != "" && w.Header().Get("Access-Control-Max-Age") != "3600" {
+				t.Errorf("expect max age 3600, but get %q", w.Header().Get("Access-Control-Max-Age"))
+			}
+		})
+	}
+}
+
+func TestCorsDefault(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	e := gin.New()
+	e.Use(Cors)
+	e.GET("/", func(c *gin.Context) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expect allow origin *, but get %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("expect no credentials, but get %q", got)
+	}
+}
+
+func TestCorsCredentialsRequireOrigins(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expect panic when credentials allow all origins")
+		}
+	}()
+	NewCors(WithCorsCredentials(true))
+}
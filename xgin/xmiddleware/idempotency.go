@@ -0,0 +1,270 @@
+package xmiddleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+
+	"skuld/ecode"
+	"skuld/requestid"
+	"skuld/xgin"
+	"skuld/xlogger"
+)
+
+const (
+	idempotencyProcessing = "processing"
+	idempotencyDone       = "done"
+)
+
+type IdempotencyOption func(*idempotencyOptions)
+
+type idempotencyOptions struct {
+	header  string
+	prefix  string
+	ttl     time.Duration
+	lockTTL time.Duration
+	maxBody int64
+	logger  xlogger.Logger
+}
+
+// WithIdempotencyHeader 设置读取幂等键的请求头, 默认 Idempotency-Key
+func WithIdempotencyHeader(header string) IdempotencyOption {
+	return func(o *idempotencyOptions) {
+		o.header = header
+	}
+}
+
+// WithIdempotencyPrefix 设置 redis key 前缀, 默认 idempotency:
+func WithIdempotencyPrefix(prefix string) IdempotencyOption {
+	return func(o *idempotencyOptions) {
+		o.prefix = prefix
+	}
+}
+
+// WithIdempotencyTTL 设置响应的保存时间, 默认 24 小时
+func WithIdempotencyTTL(ttl time.Duration) IdempotencyOption {
+	return func(o *idempotencyOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithIdempotencyLockTTL 设置请求处理中状态的最长保留时间, 防止进程崩溃后幂等键被永久占用, 默认 1 分钟
+func WithIdempotencyLockTTL(ttl time.Duration) IdempotencyOption {
+	return func(o *idempotencyOptions) {
+		o.lockTTL = ttl
+	}
+}
+
+// WithIdempotencyMaxBody 设置计算请求指纹时读取的最大请求体, 超过时返回 ecode.RequestTooLarge, 默认 1MB
+func WithIdempotencyMaxBody(n int64) IdempotencyOption {
+	return func(o *idempotencyOptions) {
+		o.maxBody = n
+	}
+}
+
+// WithIdempotencyLogger 设置 redis 读写失败时的日志
+func WithIdempotencyLogger(logger xlogger.Logger) IdempotencyOption {
+	return func(o *idempotencyOptions) {
+		o.logger = logger
+	}
+}
+
+type idempotencyRecord struct {
+	State string `json:"state"`
+	// Token 处理中状态的持有者, 只有持有者可以释放或完成, 防止锁过期后覆盖其他请求的锁
+	Token       string      `json:"token,omitempty"`
+	Fingerprint string      `json:"fingerprint"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// Idempotency 根据请求头中的幂等键保存首次请求的响应, 重复请求直接返回保存的响应
+//
+// 相同幂等键的请求正在处理时返回 ecode.IdempotencyInProgress,
+// 相同幂等键但请求内容不同时返回 ecode.IdempotencyKeyReused,
+// 5xx 响应不会被保存, 客户端可以使用相同的幂等键重试
+func Idempotency(client *redis.Client, opts ...IdempotencyOption) gin.HandlerFunc {
+	options := idempotencyOptions{
+		header:  "Idempotency-Key",
+		prefix:  "idempotency:",
+		ttl:     24 * time.Hour,
+		lockTTL: time.Minute,
+		maxBody: 1 << 20,
+	}
+	for _, option := range opts {
+		option(&options)
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(options.header)
+		if key == "" {
+			return
+		}
+		key = options.prefix + key
+		ctx := c.Request.Context()
+
+		bodydata, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, options.maxBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				xgin.Failure(c, ecode.RequestTooLarge)
+				return
+			}
+			xgin.Failure(c, err)
+			return
+		}
+		_ = c.Request.Body.Close()
+		c.Request.Body = io.NopCloser(bytes.NewReader(bodydata))
+
+		h := sha256.New()
+		h.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "?" + c.Request.URL.RawQuery + "\n"))
+		h.Write(bodydata)
+		fingerprint := hex.EncodeToString(h.Sum(nil))
+
+		lock, _ := json.Marshal(idempotencyRecord{State: idempotencyProcessing, Token: newLockToken(), Fingerprint: fingerprint})
+		ok, err := client.SetNX(ctx, key, lock, options.lockTTL).Result()
+		if err != nil {
+			// redis 不可用时不阻塞业务请求
+			if options.logger != nil {
+				options.logger.Error("Idempotency client.SetNX", "key", key, "err", err)
+			}
+			return
+		}
+
+		if !ok {
+			data, err := client.Get(ctx, key).Bytes()
+			if err != nil {
+				if errors.Is(err, redis.Nil) {
+					// 处理中状态刚好过期, 按正在处理中返回, 客户端重试即可
+					xgin.Failure(c, ecode.IdempotencyInProgress)
+					return
+				}
+				xgin.Failure(c, err)
+				return
+			}
+			record := idempotencyRecord{}
+			if err := json.Unmarshal(data, &record); err != nil {
+				xgin.Failure(c, err)
+				return
+			}
+			switch {
+			case record.Fingerprint != fingerprint:
+				xgin.Failure(c, ecode.IdempotencyKeyReused)
+			case record.State != idempotencyDone:
+				xgin.Failure(c, ecode.IdempotencyInProgress)
+			default:
+				c.Abort()
+				// 覆盖而不是追加, 保留本次请求的请求 ID, cors 等响应头
+				for k, vs := range record.Header {
+					if !perRequestHeader(k) {
+						c.Writer.Header()[k] = vs
+					}
+				}
+				c.Writer.Header().Set("Idempotent-Replayed", "true")
+				c.Writer.WriteHeader(record.Status)
+				_, _ = c.Writer.Write(record.Body)
+			}
+			return
+		}
+
+		w := newInterceptWriter(c.Writer)
+		c.Writer = w
+
+		completed := false
+		defer func() {
+			if completed && w.Status() < http.StatusInternalServerError {
+				return
+			}
+			// 处理失败或发生 panic 时释放幂等键, 允许客户端重试
+			if err := idempotencyRelease.Run(context.Background(), client, []string{key}, lock).Err(); err != nil && options.logger != nil {
+				options.logger.Error("Idempotency release", "key", key, "err", err)
+			}
+		}()
+
+		c.Next()
+		completed = true
+
+		if w.Status() >= http.StatusInternalServerError {
+			return
+		}
+		header := make(http.Header, len(w.Header()))
+		for k, vs := range w.Header() {
+			if !perRequestHeader(k) {
+				header[k] = append([]string(nil), vs...)
+			}
+		}
+		record, _ := json.Marshal(idempotencyRecord{
+			State:       idempotencyDone,
+			Fingerprint: fingerprint,
+			Status:      w.Status(),
+			Header:      header,
+			Body:        w.buf.Bytes(),
+		})
+		err = idempotencyComplete.Run(context.Background(), client, []string{key}, lock, record, options.ttl.Milliseconds()).Err()
+		if err != nil && options.logger != nil {
+			options.logger.Error("Idempotency complete", "key", key, "err", err)
+		}
+	}
+}
+
+var (
+	// idempotencyRelease 只有仍持有处理中状态时才删除, 锁过期后被其他请求获取时不删除
+	idempotencyRelease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+	// idempotencyComplete 只有仍持有处理中状态时才保存响应
+	idempotencyComplete = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+	return 1
+end
+return 0`)
+)
+
+func newLockToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// perRequestHeader 判断是否为每个请求各自生成的响应头, 这些响应头不保存也不重放
+func perRequestHeader(key string) bool {
+	key = http.CanonicalHeaderKey(key)
+	switch key {
+	case requestid.Header, "Vary", "Date", "Content-Length", "Traceparent", "Tracestate", "Idempotent-Replayed":
+		return true
+	}
+	return strings.HasPrefix(key, "Access-Control-")
+}
+
+// interceptWriter 在写出响应的同时保留完整的响应 body
+type interceptWriter struct {
+	buf *bytes.Buffer
+	gin.ResponseWriter
+}
+
+func newInterceptWriter(w gin.ResponseWriter) *interceptWriter {
+	return &interceptWriter{
+		buf:            bytes.NewBufferString(""),
+		ResponseWriter: w,
+	}
+}
+
+func (w *interceptWriter) Write(data []byte) (int, error) {
+	w.buf.Write(data)
+	return w.ResponseWriter.Write(data)
+}
@@ -0,0 +1,84 @@
+package xmiddleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+
+	"skuld/requestid"
+	"skuld/xgin"
+)
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	calls := 0
+	e := gin.New()
+	e.Use(RequestID, Idempotency(client, WithIdempotencyMaxBody(16)))
+	e.POST("/orders", func(c *gin.Context) {
+		calls++
+		c.Header("X-Order", "1")
+		xgin.Success(c, calls)
+	})
+	e.POST("/fail", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+	e.POST("/stolen", func(c *gin.Context) {
+		// 模拟处理时间超过 lockTTL, 锁过期后被其他请求获取
+		mr.Set("idempotency:stolen", "other")
+		xgin.Success(c)
+	})
+
+	do := func(path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+
+	first := do("/orders?a=1", "k1", `{}`)
+	replay := do("/orders?a=1", "k1", `{}`)
+	if calls != 1 || replay.Body.String() != first.Body.String() {
+		t.Fatalf("expect replayed response %q, but get %q after %d calls", first.Body.String(), replay.Body.String(), calls)
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" || replay.Header().Get("X-Order") != "1" {
+		t.Errorf("unexpect replayed header %v", replay.Header())
+	}
+	if ids := replay.Header().Values(requestid.Header); len(ids) != 1 || ids[0] == first.Header().Get(requestid.Header) {
+		t.Errorf("expect a new single request id, but get %v", ids)
+	}
+
+	if w := do("/orders?a=2", "k1", `{}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expect key reused with different query, but get %d", w.Code)
+	}
+
+	sum := sha256.Sum256([]byte("POST /orders?\n"))
+	mr.Set("idempotency:k2", `{"state":"processing","token":"t","fingerprint":"`+hex.EncodeToString(sum[:])+`"}`)
+	if w := do("/orders", "k2", ``); w.Code != http.StatusConflict {
+		t.Errorf("expect in progress, but get %d", w.Code)
+	}
+
+	if w := do("/orders", "k3", strings.Repeat("a", 17)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expect request too large, but get %d", w.Code)
+	}
+
+	do("/fail", "k4", ``)
+	if mr.Exists("idempotency:k4") {
+		t.Errorf("expect key released after 5xx")
+	}
+
+	do("/stolen", "stolen", ``)
+	if v, _ := mr.Get("idempotency:stolen"); v != "other" {
+		t.Errorf("expect lock of other request kept, but get %q", v)
+	}
+}
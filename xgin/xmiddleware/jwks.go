@@ -0,0 +1,277 @@
+package xmiddleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// JWT 支持的签名算法
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// minRSABits RS256 密钥模数的最小位数
+const minRSABits = 2048
+
+type jwtKey struct {
+	alg string
+	key interface{}
+}
+
+// KeySet 验证 JWT 签名的密钥集合, 以 kid 区分密钥, 可以并发地增删或整体替换以实现密钥轮换
+type KeySet struct {
+	mu   sync.RWMutex
+	keys map[string]jwtKey
+}
+
+func NewKeySet() *KeySet {
+	return &KeySet{keys: make(map[string]jwtKey)}
+}
+
+// Add 添加或替换 kid 对应的密钥, HS256 的 key 为 []byte, RS256 为 *rsa.PublicKey (至少 2048 位), ES256 为 *ecdsa.PublicKey (P-256);
+// 轮换时先添加新密钥, 等旧 token 过期后再 Remove 旧密钥
+func (s *KeySet) Add(kid, alg string, key interface{}) error {
+	if err := checkKey(alg, key); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = jwtKey{alg: alg, key: key}
+	return nil
+}
+
+// Remove 删除 kid 对应的密钥
+func (s *KeySet) Remove(kid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, kid)
+}
+
+// SetJWKS 解析 JWKS (RFC 7517) 并整体替换当前的密钥, 支持 kty 为 oct / RSA / EC (P-256) 的密钥,
+// use 不为 sig 的密钥被忽略; 解析失败时保留当前的密钥
+func (s *KeySet) SetJWKS(data []byte) error {
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	return nil
+}
+
+// LoadJWKSFile 从本地 JWKS 文件创建 KeySet
+func LoadJWKSFile(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := NewKeySet()
+	if err = s.SetJWKS(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// WatchFile 每隔 interval 检查 JWKS 文件, 修改时间变化时重新加载, 直到 ctx 结束;
+// 加载失败时保留当前的密钥并调用 onErr (可以为 nil)
+func (s *KeySet) WatchFile(ctx context.Context, path string, interval time.Duration, onErr func(error)) {
+	var modTime time.Time
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			info, err := os.Stat(path)
+			if err == nil && info.ModTime().Equal(modTime) {
+				continue
+			}
+			var data []byte
+			if err == nil {
+				data, err = os.ReadFile(path)
+			}
+			if err == nil {
+				err = s.SetJWKS(data)
+			}
+			if err != nil {
+				if onErr != nil {
+					onErr(err)
+				}
+				continue
+			}
+			modTime = info.ModTime()
+		}
+	}()
+}
+
+// lookup 返回 kid 对应的密钥, token 没有 kid 时使用唯一一个算法匹配的密钥
+func (s *KeySet) lookup(kid, alg string) (interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if kid != "" {
+		k, ok := s.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("jwt: unknown kid %q", kid)
+		}
+		// 密钥只能用于声明的算法, 防止用公钥作为 HS256 的密钥伪造签名
+		if k.alg != alg {
+			return nil, fmt.Errorf("jwt: kid %q does not support alg %s", kid, alg)
+		}
+		return k.key, nil
+	}
+	var found interface{}
+	for _, k := range s.keys {
+		if k.alg != alg {
+			continue
+		}
+		if found != nil {
+			return nil, errors.New("jwt: kid is required")
+		}
+		found = k.key
+	}
+	if found == nil {
+		return nil, fmt.Errorf("jwt: no key for alg %s", alg)
+	}
+	return found, nil
+}
+
+func checkKey(alg string, key interface{}) error {
+	var ok bool
+	switch alg {
+	case HS256:
+		var b []byte
+		b, ok = key.([]byte)
+		ok = ok && len(b) > 0
+	case RS256:
+		var k *rsa.PublicKey
+		k, ok = key.(*rsa.PublicKey)
+		if ok && k.N.BitLen() < minRSABits {
+			return fmt.Errorf("jwt: rsa key must be at least %d bits", minRSABits)
+		}
+	case ES256:
+		var k *ecdsa.PublicKey
+		k, ok = key.(*ecdsa.PublicKey)
+		ok = ok && k.Curve == elliptic.P256()
+	default:
+		return fmt.Errorf("jwt: unsupported alg %s", alg)
+	}
+	if !ok {
+		return fmt.Errorf("jwt: invalid key %T for alg %s", key, alg)
+	}
+	return nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func parseJWKS(data []byte) (map[string]jwtKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwt: invalid jwks: %w", err)
+	}
+	keys := make(map[string]jwtKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.parse()
+		if err != nil {
+			return nil, fmt.Errorf("jwt: invalid jwk %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jwk) parse() (jwtKey, error) {
+	var (
+		alg string
+		key interface{}
+	)
+	switch k.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return jwtKey{}, err
+		}
+		alg, key = HS256, secret
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return jwtKey{}, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return jwtKey{}, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return jwtKey{}, errors.New("invalid exponent")
+		}
+		alg, key = RS256, &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		if k.Crv != "P-256" {
+			return jwtKey{}, fmt.Errorf("unsupported crv %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return jwtKey{}, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return jwtKey{}, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return jwtKey{}, errors.New("point is not on curve")
+		}
+		alg, key = ES256, &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	default:
+		return jwtKey{}, fmt.Errorf("unsupported kty %s", k.Kty)
+	}
+	if k.Alg != "" && k.Alg != alg {
+		return jwtKey{}, fmt.Errorf("unsupported alg %s", k.Alg)
+	}
+	return jwtKey{alg: alg, key: key}, checkKey(alg, key)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
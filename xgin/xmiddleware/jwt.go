@@ -0,0 +1,224 @@
+package xmiddleware
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"skuld/ecode"
+	"skuld/xgin"
+)
+
+// Claims JWT 中的标准字段以及角色和权限, 其他字段通过 Get 读取
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+
+	raw map[string]interface{}
+}
+
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	type claims Claims
+	if err := json.Unmarshal(data, (*claims)(c)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &c.raw)
+}
+
+// Get 返回 JWT 中名称为 key 的字段, 数字为 float64
+func (c *Claims) Get(key string) (interface{}, bool) {
+	v, ok := c.raw[key]
+	return v, ok
+}
+
+// HasRole 判断是否拥有 roles 中的任意一个角色
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		for _, r := range c.Roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasPermissions 判断是否拥有 perms 中的全部权限
+func (c *Claims) HasPermissions(perms ...string) bool {
+	for _, perm := range perms {
+		found := false
+		for _, p := range c.Permissions {
+			if p == perm {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type claimsKey struct{}
+
+const claimsCtxKey = "JWTClaims"
+
+// ClaimsFromContext 返回 JWT 中间件保存在 c.Request.Context() 中的 Claims
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// GetClaims 返回 JWT 中间件保存的 Claims, 未通过认证时返回 nil
+func GetClaims(c *gin.Context) *Claims {
+	if v, ok := c.Get(claimsCtxKey); ok {
+		if claims, ok := v.(*Claims); ok {
+			return claims
+		}
+	}
+	return nil
+}
+
+type JWTOption func(*jwtOptions)
+
+type jwtOptions struct {
+	issuer   string
+	audience string
+	leeway   time.Duration
+	optional bool
+	token    func(c *gin.Context) string
+}
+
+// WithJWTIssuer 校验 iss 必须为 issuer
+func WithJWTIssuer(issuer string) JWTOption {
+	return func(o *jwtOptions) {
+		o.issuer = issuer
+	}
+}
+
+// WithJWTAudience 校验 aud 必须包含 audience
+func WithJWTAudience(audience string) JWTOption {
+	return func(o *jwtOptions) {
+		o.audience = audience
+	}
+}
+
+// WithJWTLeeway 设置校验 exp / nbf / iat 时允许的时钟误差, 默认 0
+func WithJWTLeeway(leeway time.Duration) JWTOption {
+	return func(o *jwtOptions) {
+		o.leeway = leeway
+	}
+}
+
+// WithJWTOptional 请求没有 token 时不返回错误, 之后的 handler 通过 GetClaims 是否为 nil 判断是否登录,
+// 携带了无效 token 的请求仍返回 ecode.Unauthorized
+func WithJWTOptional() JWTOption {
+	return func(o *jwtOptions) {
+		o.optional = true
+	}
+}
+
+// WithJWTTokenLookup 设置读取 token 的方式, 默认读取 Authorization: Bearer <token>
+func WithJWTTokenLookup(lookup func(c *gin.Context) string) JWTOption {
+	return func(o *jwtOptions) {
+		o.token = lookup
+	}
+}
+
+// bearerToken 读取 Authorization 请求头中的 Bearer token
+func bearerToken(c *gin.Context) string {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// JWT 使用 keys 中的密钥验证请求的 JWT (HS256 / RS256 / ES256), 并校验 exp (必须存在), nbf, 以及设置了时的 iss 和 aud,
+// 验证通过后将 Claims 保存到 gin.Context 和 c.Request.Context() 中, 失败时设置 WWW-Authenticate: Bearer 并返回 ecode.Unauthorized
+func JWT(keys *KeySet, opts ...JWTOption) gin.HandlerFunc {
+	options := jwtOptions{
+		token: bearerToken,
+	}
+	for _, option := range opts {
+		option(&options)
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{HS256, RS256, ES256}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(options.leeway),
+	}
+	if options.issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(options.issuer))
+	}
+	if options.audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(options.audience))
+	}
+	parser := jwt.NewParser(parserOpts...)
+	keyfunc := func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return keys.lookup(kid, token.Method.Alg())
+	}
+
+	return func(c *gin.Context) {
+		tokenString := options.token(c)
+		if tokenString == "" {
+			if !options.optional {
+				unauthorized(c, "Bearer")
+			}
+			return
+		}
+
+		claims := &Claims{}
+		if _, err := parser.ParseWithClaims(tokenString, claims, keyfunc); err != nil {
+			unauthorized(c, `Bearer error="invalid_token"`)
+			return
+		}
+
+		c.Set(claimsCtxKey, claims)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), claimsKey{}, claims))
+	}
+}
+
+// unauthorized 按 RFC 6750 设置 WWW-Authenticate 后返回 ecode.Unauthorized
+func unauthorized(c *gin.Context, challenge string) {
+	c.Header("WWW-Authenticate", challenge)
+	xgin.Failure(c, ecode.Unauthorized)
+}
+
+// RequireRoles 要求拥有 roles 中的任意一个角色, 需要在 JWT 之后使用;
+// 未登录时返回 ecode.Unauthorized, 没有角色时返回 ecode.Forbidden
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := GetClaims(c)
+		if claims == nil {
+			unauthorized(c, "Bearer")
+			return
+		}
+		if !claims.HasRole(roles...) {
+			xgin.Failure(c, ecode.Forbidden)
+		}
+	}
+}
+
+// RequirePermissions 要求拥有 perms 中的全部权限, 需要在 JWT 之后使用;
+// 未登录时返回 ecode.Unauthorized, 缺少权限时返回 ecode.Forbidden
+func RequirePermissions(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := GetClaims(c)
+		if claims == nil {
+			unauthorized(c, "Bearer")
+			return
+		}
+		if !claims.HasPermissions(perms...) {
+			xgin.Failure(c, ecode.Forbidden)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package xmiddleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	_ "skuld/encoding/json"
+)
+
+type testClaims struct {
+	jwt.RegisteredClaims
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+func TestJWT(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("secret")
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"oct","kid":"hs","k":%q},{"kty":"EC","kid":"es","crv":"P-256","x":%q,"y":%q},%s]}`,
+		base64.RawURLEncoding.EncodeToString(secret),
+		base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+		base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))),
+		rsaJWK("rs", &rsaKey.PublicKey),
+	)
+	keys := NewKeySet()
+	if err = keys.SetJWKS([]byte(jwks)); err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}, claims testClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	valid := func(roles ...string) testClaims {
+		return testClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "1",
+				Issuer:    "skuld",
+				Audience:  jwt.ClaimStrings{"api"},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			Roles:       roles,
+			Permissions: []string{"user:read"},
+		}
+	}
+	expired := valid("admin")
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	notBefore := valid("admin")
+	notBefore.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	otherAudience := valid("admin")
+	otherAudience.Audience = jwt.ClaimStrings{"web"}
+
+	e := gin.New()
+	auth := e.Group("/", JWT(keys, WithJWTIssuer("skuld"), WithJWTAudience("api")))
+	auth.GET("/me", func(c *gin.Context) {
+		claims, _ := ClaimsFromContext(c.Request.Context())
+		c.String(http.StatusOK, claims.Subject)
+	})
+	auth.GET("/admin", RequireRoles("admin"), func(c *gin.Context) {})
+	auth.GET("/users", RequirePermissions("user:read", "user:write"), func(c *gin.Context) {})
+
+	type testCase struct {
+		name   string
+		path   string
+		token  string
+		status int
+	}
+	testTable := []testCase{
+		{name: "hs256", path: "/me", token: sign(jwt.SigningMethodHS256, "hs", secret, valid()), status: http.StatusOK},
+		{name: "es256", path: "/me", token: sign(jwt.SigningMethodES256, "es", ecKey, valid()), status: http.StatusOK},
+		{name: "rs256", path: "/me", token: sign(jwt.SigningMethodRS256, "rs", rsaKey, valid()), status: http.StatusOK},
+		{name: "missing token", path: "/me", status: http.StatusUnauthorized},
+		{name: "wrong secret", path: "/me", token: sign(jwt.SigningMethodHS256, "hs", []byte("other"), valid()), status: http.StatusUnauthorized},
+		{name: "alg mismatch kid", path: "/me", token: sign(jwt.SigningMethodHS256, "es", secret, valid()), status: http.StatusUnauthorized},
+		{name: "unknown kid", path: "/me", token: sign(jwt.SigningMethodHS256, "other", secret, valid()), status: http.StatusUnauthorized},
+		{name: "expired", path: "/me", token: sign(jwt.SigningMethodHS256, "hs", secret, expired), status: http.StatusUnauthorized},
+		{name: "not before", path: "/me", token: sign(jwt.SigningMethodHS256, "hs", secret, notBefore), status: http.StatusUnauthorized},
+		{name: "audience", path: "/me", token: sign(jwt.SigningMethodHS256, "hs", secret, otherAudience), status: http.StatusUnauthorized},
+		{name: "role", path: "/admin", token: sign(jwt.SigningMethodHS256, "hs", secret, valid("admin")), status: http.StatusOK},
+		{name: "missing role", path: "/admin", token: sign(jwt.SigningMethodHS256, "hs", secret, valid("user")), status: http.StatusForbidden},
+		{name: "missing permission", path: "/users", token: sign(jwt.SigningMethodHS256, "hs", secret, valid("admin")), status: http.StatusForbidden},
+	}
+
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, v.path, nil)
+			if v.token != "" {
+				req.Header.Set("Authorization", "Bearer "+v.token)
+			}
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, req)
+
+			if w.Code != v.status {
+				t.Errorf("expect status %d, but get %d: %s", v.status, w.Code, w.Body.String())
+			}
+			if challenge := w.Header().Get("WWW-Authenticate"); (w.Code == http.StatusUnauthorized) != strings.HasPrefix(challenge, "Bearer") {
+				t.Errorf("expect WWW-Authenticate: Bearer only on 401, but get %q", challenge)
+			}
+		})
+	}
+
+	// 轮换后旧密钥签名的 token 失效
+	keys.Remove("hs")
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+sign(jwt.SigningMethodHS256, "hs", secret, valid()))
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expect status %d after rotation, but get %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) string {
+	return fmt.Sprintf(`{"kty":"RSA","kid":%q,"n":%q,"e":%q}`, kid,
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()))
+}
+
+func TestKeySetRSABits(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := NewKeySet()
+	if err = keys.Add("weak", RS256, &weak.PublicKey); err == nil {
+		t.Error("expect 1024 bits rsa key rejected")
+	}
+	if err = keys.SetJWKS([]byte(`{"keys":[` + rsaJWK("weak", &weak.PublicKey) + `]}`)); err == nil {
+		t.Error("expect 1024 bits rsa jwk rejected")
+	}
+}
+
+func TestKeySetWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	write := func(kid string, modTime time.Time) {
+		data := fmt.Sprintf(`{"keys":[{"kty":"oct","kid":%q,"k":%q}]}`, kid, base64.RawURLEncoding.EncodeToString([]byte(kid)))
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	write("old", now)
+
+	keys, err := LoadJWKSFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = keys.lookup("old", HS256); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 1)
+	keys.WatchFile(ctx, path, 10*time.Millisecond, func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+
+	waitFor := func(kid string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			if _, err := keys.lookup(kid, HS256); err == nil {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expect key %s loaded", kid)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// 文件修改后整体替换密钥
+	write("new", now.Add(time.Second))
+	waitFor("new")
+	if _, err = keys.lookup("old", HS256); err == nil {
+		t.Error("expect old key removed after rotation")
+	}
+
+	// 加载失败时保留当前的密钥
+	select {
+	case <-errs:
+	default:
+	}
+	if err = os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(path, now.Add(2*time.Second), now.Add(2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-errs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expect onErr called for invalid jwks")
+	}
+	if _, err = keys.lookup("new", HS256); err != nil {
+		t.Errorf("expect current key kept, but get %v", err)
+	}
+}
@@ -5,141 +5,302 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"math/rand"
-	"runtime/debug"
+	"mime"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 
-	"skuld/xgin"
+	"skuld/requestid"
 	"skuld/xlogger"
+	"skuld/xlogger/redact"
+	"skuld/xlogger/report"
 )
 
-type interceptWriter struct {
-	buf *bytes.Buffer
-	gin.ResponseWriter
+type LogOption func(*logOptions)
+
+type logOptions struct {
+	redactor     *redact.Redactor
+	skipPaths    []string
+	skipMethods  []string
+	maxRequest   int
+	maxResponse  int
+	contentTypes []string
+	recovery     bool
+	reporter     report.Reporter
 }
 
-func newInterceptWriter(w gin.ResponseWriter) *interceptWriter {
-	return &interceptWriter{
-		buf:            bytes.NewBufferString(""),
-		ResponseWriter: w,
+// WithLogRedactor 设置请求 / 响应的头和 body 的脱敏规则, 默认 redact.Default(), 传入 nil 表示不脱敏
+func WithLogRedactor(r *redact.Redactor) LogOption {
+	return func(o *logOptions) {
+		o.redactor = r
 	}
 }
 
-func (w *interceptWriter) Write(data []byte) (int, error) {
-	w.buf.Write(data)
-	return w.ResponseWriter.Write(data)
+// WithLogRecovery 设置 Log 是否恢复 panic, 默认 true, 此时 Log 内部使用 Recovery 处理 panic, 包括不记录日志的路径.
+//
+// Deprecated: 使用 WithLogRecovery(false) 关闭, 并在 Log 之后注册 Recovery 中间件
+func WithLogRecovery(recovery bool) LogOption {
+	return func(o *logOptions) {
+		o.recovery = recovery
+	}
+}
+
+// WithLogReporter 将恢复的 panic 连同调用栈上报给 r, logger 已通过 xlogger.WithReporter 上报 Error 日志时无需设置
+//
+// Deprecated: 使用 Recovery 中间件以及 WithRecoveryReporter
+func WithLogReporter(r report.Reporter) LogOption {
+	return func(o *logOptions) {
+		o.reporter = r
+	}
+}
+
+// WithLogSkipPaths 设置不记录日志的路径, 以 * 结尾表示前缀匹配, 以 * 开头表示后缀匹配, 默认 */ping
+func WithLogSkipPaths(paths ...string) LogOption {
+	return func(o *logOptions) {
+		o.skipPaths = paths
+	}
+}
+
+// WithLogSkipMethods 设置不记录日志的请求方法, 如 OPTIONS / HEAD
+func WithLogSkipMethods(methods ...string) LogOption {
+	return func(o *logOptions) {
+		o.skipMethods = methods
+	}
+}
+
+// WithLogMaxBody 设置请求 / 响应 body 最多记录的字节数, 超出部分截断, 0 表示不记录, 默认均为 10KB
+func WithLogMaxBody(request, response int) LogOption {
+	return func(o *logOptions) {
+		o.maxRequest = request
+		o.maxResponse = response
+	}
+}
+
+// WithLogContentTypes 设置记录 body 的 Content-Type, 支持 text/* 形式的通配, 其他类型 (如文件, 图片) 只记录大小,
+// 默认 application/json, application/xml, application/x-www-form-urlencoded, text/plain, text/xml
+func WithLogContentTypes(types ...string) LogOption {
+	return func(o *logOptions) {
+		o.contentTypes = types
+	}
 }
 
-func Log(logger xlogger.Logger) gin.HandlerFunc {
+// Log 在请求结束后记录一条访问日志, 包含请求方法, 路由, 状态码, 耗时 (毫秒), 请求 / 响应的头和 body;
+// 请求 body 只预读 WithLogMaxBody 指定的字节数, 响应 body 只保留同样大小的前缀, 调用 Flush 的流式响应不记录 body.
+// 默认通过 Recovery 恢复 handler 中的 panic, 见 WithLogRecovery
+func Log(logger xlogger.Logger, opts ...LogOption) gin.HandlerFunc {
+	options := logOptions{
+		redactor:    redact.Default(),
+		skipPaths:   []string{"*/ping"},
+		maxRequest:  10 * 1024,
+		maxResponse: 10 * 1024,
+		contentTypes: []string{
+			"application/json",
+			"application/xml",
+			"application/x-www-form-urlencoded",
+			"text/plain",
+			"text/xml",
+		},
+		recovery: true,
+	}
+	for _, option := range opts {
+		option(&options)
+	}
+	redactor := options.redactor
+	if redactor == nil {
+		redactor = redact.New()
+	}
+	next := func(c *gin.Context) { c.Next() }
+	if options.recovery {
+		next = Recovery(logger, WithRecoveryReporter(options.reporter))
+	}
+
 	return func(c *gin.Context) {
-		if strings.HasSuffix(c.Request.RequestURI, "/ping") {
+		if options.skip(c.Request) {
+			next(c)
 			return
 		}
 
-		requestId := rand.Int63()
+		// 未使用 RequestID 中间件时在此生成请求 ID
+		if requestid.FromContext(c.Request.Context()) == "" {
+			c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), requestid.New()))
+		}
+		logger := xlogger.WithContext(c.Request.Context(), logger)
 		start := time.Now()
 
-		bodydata, err := io.ReadAll(c.Request.Body)
-		if err != nil {
-			logger.Error("Log io.ReadAll", "err", err)
-			return
+		var reqbody *bodyCapture
+		if options.maxRequest > 0 && c.Request.Body != nil && c.Request.Body != http.NoBody &&
+			options.capture(c.ContentType()) {
+			head, err := io.ReadAll(io.LimitReader(c.Request.Body, int64(options.maxRequest)+1))
+			if err != nil {
+				logger.Error("Log io.ReadAll", "err", err)
+			}
+			// 已读取的部分放回 body, handler 仍能读到完整的请求
+			c.Request.Body = readCloser{
+				Reader: io.MultiReader(bytes.NewReader(head), c.Request.Body),
+				Closer: c.Request.Body,
+			}
+			reqbody = newBodyCapture(options.maxRequest, c.ContentType())
+			reqbody.write(head)
+		}
+
+		w := &captureWriter{ResponseWriter: c.Writer, options: &options}
+		c.Writer = w
+
+		next(c)
+
+		kvs := []interface{}{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", c.Writer.Status(),
+			"latency", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+			"request_size", c.Request.ContentLength,
+			"response_size", c.Writer.Size(),
+
+			"request_host", c.Request.Host,
+			"request_uri", c.Request.RequestURI,
+			"request_header", redactor.Header(c.Request.Header),
+			"request_body", reqbody.log(redactor),
+			"response_header", redactor.Header(c.Writer.Header()),
+			"response_body", w.body.log(redactor),
+		}
+		if errs := c.Errors.ByType(gin.ErrorTypePrivate); len(errs) > 0 {
+			kvs = append(kvs, "errors", errs.String())
 		}
-		err = c.Request.Body.Close()
-		if err != nil {
-			logger.Error("Log c.Request.Body.Close", "err", err)
+		logger.Info("access log", kvs...)
+	}
+}
+
+func (o *logOptions) skip(r *http.Request) bool {
+	for _, m := range o.skipMethods {
+		if strings.EqualFold(m, r.Method) {
+			return true
 		}
+	}
+	path := r.URL.Path
+	for _, p := range o.skipPaths {
+		switch {
+		case strings.HasSuffix(p, "*"):
+			if strings.HasPrefix(path, strings.TrimSuffix(p, "*")) {
+				return true
+			}
+		case strings.HasPrefix(p, "*"):
+			if strings.HasSuffix(path, strings.TrimPrefix(p, "*")) {
+				return true
+			}
+		case p == path:
+			return true
+		}
+	}
+	return false
+}
 
-		var bodylog interface{}
-		if len(bodydata) > 10*1024 { // 10kb 不记录 body
-			bodylog = fmt.Sprintf("body too large: %d byte", len(bodydata))
-		} else {
-			bodymap := make(map[string]interface{})
-			err = json.Unmarshal(bodydata, &bodymap)
-			if err != nil {
-				bodylog = string(bodydata)
-			} else {
-				bodylog = bodymap
+// capture 判断 Content-Type 是否在允许记录 body 的列表中
+func (o *logOptions) capture(contentType string) bool {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if contentType == "" {
+		return false
+	}
+	for _, t := range o.contentTypes {
+		if strings.HasSuffix(t, "/*") {
+			if strings.HasPrefix(contentType, strings.TrimSuffix(t, "*")) {
+				return true
 			}
+		} else if t == contentType {
+			return true
 		}
+	}
+	return false
+}
 
-		logger.Info("request log", "request", map[string]interface{}{
-			"request_id":     requestId,
-			"request_host":   c.Request.Host,
-			"request_uri":    c.Request.RequestURI,
-			"request_header": c.Request.Header,
-			"request_body":   bodylog,
-		})
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
 
-		c.Request.Body = io.NopCloser(bytes.NewReader(bodydata))
-		w := newInterceptWriter(c.Writer)
-		c.Writer = w
+// bodyCapture 最多保留 max 字节的 body
+type bodyCapture struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+	// form body 为 application/x-www-form-urlencoded, 按表单字段脱敏
+	form bool
+}
 
-		defer func() {
-			if r := recover(); r != nil {
-				xgin.Failure(c, fmt.Errorf(recoverString(r)))
-				var resplog interface{}
-				respmap := make(map[string]interface{})
-				err = json.Unmarshal(w.buf.Bytes(), &respmap)
-				if err != nil {
-					resplog = w.buf.String()
-				} else {
-					resplog = respmap
-				}
-				logger.Error("response panic log", "response", map[string]interface{}{
-					"request_id":           requestId,
-					"response_header":      c.Writer.Header(),
-					"response_status_code": c.Writer.Status(),
-					"response_body":        resplog,
-
-					"panic": r,
-					"stack": string(debug.Stack()),
-
-					"request_host":   c.Request.Host,
-					"request_uri":    c.Request.RequestURI,
-					"request_header": c.Request.Header,
-					"request_body":   bodylog,
-
-					"duration": time.Since(start).Milliseconds(),
-				})
-			}
-		}()
+func newBodyCapture(max int, contentType string) *bodyCapture {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return &bodyCapture{max: max, form: mediaType == binding.MIMEPOSTForm}
+}
 
-		c.Next()
+func (b *bodyCapture) write(data []byte) {
+	remain := b.max - b.buf.Len()
+	if len(data) > remain {
+		data = data[:remain]
+		b.truncated = true
+	}
+	b.buf.Write(data)
+}
 
-		var resplog interface{}
-		respmap := make(map[string]interface{})
-		err = json.Unmarshal(w.buf.Bytes(), &respmap)
-		if err != nil {
-			resplog = w.buf.String()
-		} else {
-			resplog = respmap
+func (b *bodyCapture) log(redactor *redact.Redactor) interface{} {
+	if b == nil {
+		return nil
+	}
+	if b.truncated {
+		// 截断后的 json 和表单无法按字段脱敏, 不记录内容
+		if data := bytes.TrimSpace(b.buf.Bytes()); b.form || bytes.HasPrefix(data, []byte("{")) || bytes.HasPrefix(data, []byte("[")) {
+			return fmt.Sprintf("body truncated: more than %d byte", b.max)
 		}
-		logger.Info("response log", "response", map[string]interface{}{
-			"request_id":           requestId,
-			"response_header":      c.Writer.Header(),
-			"response_status_code": c.Writer.Status(),
-			"response_body":        resplog,
+		return redactor.String(b.buf.String()) + "...(truncated)"
+	}
+	if b.form {
+		return string(redactor.Form(b.buf.Bytes()))
+	}
+	var v interface{}
+	if err := json.Unmarshal(b.buf.Bytes(), &v); err != nil {
+		return redactor.String(b.buf.String())
+	}
+	return redactor.Value(v)
+}
 
-			"request_host":   c.Request.Host,
-			"request_uri":    c.Request.RequestURI,
-			"request_header": c.Request.Header,
-			"request_body":   bodylog,
+// captureWriter 在写出响应的同时保留响应 body 的前缀, Content-Type 不在允许列表或调用了 Flush 时不保留
+type captureWriter struct {
+	gin.ResponseWriter
+	options *logOptions
+	body    *bodyCapture
+	checked bool
+}
 
-			"duration": time.Since(start).Milliseconds(),
-		})
+func (w *captureWriter) collect(data []byte) {
+	if !w.checked {
+		w.checked = true
+		if w.options.maxResponse > 0 && w.options.capture(w.Header().Get("Content-Type")) {
+			w.body = newBodyCapture(w.options.maxResponse, w.Header().Get("Content-Type"))
+		}
+	}
+	if w.body != nil {
+		w.body.write(data)
 	}
 }
 
-func recoverString(r interface{}) string {
-	switch v := r.(type) {
-	case error:
-		return v.Error()
-	case string:
-		return v
-	default:
-		return "服务器异常"
-	}
+func (w *captureWriter) Write(data []byte) (int, error) {
+	w.collect(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.collect([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *captureWriter) Flush() {
+	w.checked = true
+	w.body = nil
+	w.ResponseWriter.Flush()
 }
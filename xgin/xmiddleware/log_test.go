@@ -0,0 +1,68 @@
+package xmiddleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"skuld/xlogger/xloggertest"
+)
+
+func TestLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := xloggertest.NewT(t)
+
+	e := gin.New()
+	e.Use(Log(logger, WithLogMaxBody(8, 8), WithLogSkipMethods(http.MethodOptions)))
+	e.POST("/echo", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Data(http.StatusOK, "text/plain", body)
+	})
+	e.POST("/file", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/octet-stream", []byte("binary"))
+	})
+	e.GET("/v1/ping", func(c *gin.Context) {})
+	e.OPTIONS("/echo", func(c *gin.Context) {})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("0123456789"))
+	req.Header.Set("Content-Type", "text/plain")
+	e.ServeHTTP(w, req)
+	if w.Body.String() != "0123456789" {
+		t.Errorf("handler should read the full body, but get %q", w.Body.String())
+	}
+	logger.AssertLogged(t, xloggertest.InfoLevel, "access log",
+		"route", "/echo", "status", http.StatusOK,
+		"request_body", "01234567...(truncated)", "response_body", "01234567...(truncated)")
+
+	logger.Reset()
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/file", nil))
+	logger.AssertLogged(t, xloggertest.InfoLevel, "access log", "response_body", nil, "response_size", 6)
+
+	logger.Reset()
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/ping", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodOptions, "/echo", nil))
+	logger.AssertNotLogged(t, xloggertest.InfoLevel, "access log")
+}
+
+func TestLogForm(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := xloggertest.NewT(t)
+
+	e := gin.New()
+	e.Use(Log(logger, WithLogMaxBody(64, 64)))
+	e.POST("/login", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/x-www-form-urlencoded", []byte("token=abc&user=tom"))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("password=123456&user=tom"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	logger.AssertLogged(t, xloggertest.InfoLevel, "access log",
+		"request_body", "password=%2A%2A%2A%2A%2A%2A&user=tom",
+		"response_body", "token=%2A%2A%2A%2A%2A%2A&user=tom")
+}
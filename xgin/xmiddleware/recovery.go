@@ -0,0 +1,121 @@
+package xmiddleware
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"skuld/ecode"
+	"skuld/requestid"
+	"skuld/xgin"
+	"skuld/xlogger"
+	"skuld/xlogger/report"
+)
+
+// PanicHandler 处理恢复后的 panic, 负责向客户端返回响应
+type PanicHandler func(c *gin.Context, recovered interface{})
+
+type RecoveryOption func(*recoveryOptions)
+
+type recoveryOptions struct {
+	handler  PanicHandler
+	reporter report.Reporter
+}
+
+// WithPanicHandler 自定义 panic 的响应, 默认通过 xgin.Failure 返回 ecode.ServerPanic, 响应已写出时只终止请求
+func WithPanicHandler(h PanicHandler) RecoveryOption {
+	return func(o *recoveryOptions) {
+		o.handler = h
+	}
+}
+
+// WithRecoveryReporter 将 panic 连同调用栈上报给 r, logger 已通过 xlogger.WithReporter 上报 Error 日志时无需设置
+func WithRecoveryReporter(r report.Reporter) RecoveryOption {
+	return func(o *recoveryOptions) {
+		o.reporter = r
+	}
+}
+
+func defaultPanicHandler(c *gin.Context, _ interface{}) {
+	if c.Writer.Written() {
+		c.Abort()
+		return
+	}
+	xgin.Failure(c, ecode.ServerPanic)
+}
+
+// Recovery 恢复 handler 中的 panic, 记录一次 Error 日志后返回错误响应, 调用栈由 logger 输出 (ZapLogger 在 Error 级别附带 stacktrace);
+// 客户端断开连接 (broken pipe) 导致的 panic 只记录 Warn 日志, 不再写响应
+//
+// Log 默认已经包含 Recovery; 单独使用时通过 WithLogRecovery(false) 关闭 Log 中的恢复, 并将 Recovery 注册在 Log 之后,
+// 使 Log 能记录 panic 转换后的响应
+func Recovery(logger xlogger.Logger, opts ...RecoveryOption) gin.HandlerFunc {
+	options := recoveryOptions{
+		handler: defaultPanicHandler,
+	}
+	for _, option := range opts {
+		option(&options)
+	}
+
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			logger := xlogger.WithContext(c.Request.Context(), logger)
+
+			if isBrokenPipe(r) {
+				logger.Warn("Recovery broken pipe",
+					"err", r, "request_method", c.Request.Method, "request_uri", c.Request.RequestURI)
+				if err, ok := r.(error); ok {
+					_ = c.Error(err)
+				}
+				c.Abort()
+				return
+			}
+
+			logger.Error("Recovery panic",
+				"panic", r, "request_method", c.Request.Method, "request_uri", c.Request.RequestURI)
+			if options.reporter != nil {
+				options.reporter.Report(report.Event{
+					Level:   "panic",
+					Message: fmt.Sprint(r),
+					Stack:   string(debug.Stack()),
+					Fields: map[string]interface{}{
+						"request_id":     requestid.FromContext(c.Request.Context()),
+						"request_method": c.Request.Method,
+						"request_host":   c.Request.Host,
+						"request_uri":    c.Request.RequestURI,
+					},
+				})
+			}
+			options.handler(c, r)
+		}()
+
+		c.Next()
+	}
+}
+
+// isBrokenPipe 判断 panic 是否由客户端断开连接后写响应引起
+func isBrokenPipe(r interface{}) bool {
+	err, ok := r.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if !errors.As(opErr, &syscallErr) {
+		return false
+	}
+	msg := strings.ToLower(syscallErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
@@ -0,0 +1,67 @@
+package xmiddleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"skuld/xlogger/xloggertest"
+)
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := xloggertest.NewT(t)
+	logger.ExpectError("Recovery panic")
+
+	e := gin.New()
+	e.Use(Log(logger, WithLogRecovery(false)), Recovery(logger))
+	e.GET("/ping", func(c *gin.Context) {
+		panic("boom")
+	})
+	e.GET("/pipe", func(c *gin.Context) {
+		panic(&net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.EPIPE)})
+	})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expect status 500, but get %d", w.Code)
+	}
+	logger.AssertLogged(t, xloggertest.ErrorLevel, "Recovery panic", "panic", "boom")
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pipe", nil))
+	if len(logger.FilterMessage("Recovery broken pipe")) != 1 {
+		t.Errorf("expect broken pipe logged, but get %v", logger.Entries())
+	}
+	if len(logger.FilterMessage("Recovery panic")) != 1 {
+		t.Errorf("expect broken pipe not logged as panic")
+	}
+}
+
+func TestLogRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := xloggertest.NewT(t)
+	logger.ExpectError("Recovery panic")
+
+	e := gin.New()
+	e.Use(Log(logger))
+	e.GET("/ping", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	// 不记录日志的路径同样恢复 panic
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expect status 500, but get %d", w.Code)
+	}
+	if len(logger.FilterMessage("Recovery panic")) != 1 {
+		t.Errorf("expect panic logged once, but get %v", logger.Entries())
+	}
+}
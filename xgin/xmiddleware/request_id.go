@@ -0,0 +1,20 @@
+package xmiddleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"skuld/requestid"
+)
+
+// RequestID 读取请求头中的 X-Request-Id, 不存在或不合法时生成新的请求 ID,
+// 保存到 c.Request.Context() 中并通过响应头返回
+func RequestID(c *gin.Context) {
+	id := c.GetHeader(requestid.Header)
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+
+	c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+	c.Set("RequestId", id)
+	c.Writer.Header().Set(requestid.Header, id)
+}
@@ -0,0 +1,71 @@
+package xmiddleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"skuld/deadline"
+	"skuld/ecode"
+	"skuld/xgin"
+)
+
+type TimeoutOption func(*timeoutOptions)
+
+type timeoutOptions struct {
+	header string
+}
+
+// WithTimeoutHeader 设置读取客户端剩余时间 (毫秒) 的请求头, 默认 deadline.Header, 为空时不读取
+func WithTimeoutHeader(header string) TimeoutOption {
+	return func(o *timeoutOptions) {
+		o.header = header
+	}
+}
+
+// Timeout 为 c.Request.Context() 设置 timeout 的超时时间, 可以在路由组或单个路由上使用不同的超时时间, timeout 为 0 时只使用客户端的剩余时间;
+// 客户端通过请求头传入的剩余时间更短时使用客户端的剩余时间, 已经为 0 时直接返回 ecode.Timeout.
+//
+// 超时后 xorm, xsql 和 transport/http.Client 的调用返回 context.DeadlineExceeded, 交给 xgin.Failure 时返回 ecode.Timeout;
+// handler 超时后仍未写出响应时同样返回 ecode.Timeout.
+//
+// 中间件不会中断 handler, 也不会在 handler 返回前写出超时响应: handler 忽略 ctx 一直运行时, 客户端要等 handler 返回后才收到响应.
+// 因此 handler 中的阻塞调用都需要传入 c.Request.Context(), 长时间的计算需要自行检查 ctx.Done()
+func Timeout(timeout time.Duration, opts ...TimeoutOption) gin.HandlerFunc {
+	options := timeoutOptions{
+		header: deadline.Header,
+	}
+	for _, option := range opts {
+		option(&options)
+	}
+
+	return func(c *gin.Context) {
+		d, limited := timeout, timeout > 0
+		if options.header != "" {
+			if v := c.GetHeader(options.header); v != "" {
+				if remaining, ok := deadline.Parse(v); ok && (!limited || remaining < d) {
+					d, limited = remaining, true
+				}
+			}
+		}
+		if !limited {
+			return
+		}
+		if d <= 0 {
+			xgin.Failure(c, ecode.Timeout)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if !c.Writer.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			xgin.Failure(c, ecode.Timeout)
+		}
+	}
+}
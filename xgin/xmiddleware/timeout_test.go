@@ -0,0 +1,104 @@
+package xmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"skuld/deadline"
+	"skuld/ecode"
+	"skuld/encoding"
+	thttp "skuld/transport/http"
+	"skuld/xgin"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 超时的请求在下游仍可能运行, 与后续请求并发写入
+	var (
+		mu               sync.Mutex
+		downstreamBudget string
+	)
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		downstreamBudget = r.Header.Get(deadline.Header)
+		mu.Unlock()
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer downstream.Close()
+	client, err := thttp.NewClient(thttp.WithEndpoint(downstream.URL), thttp.WithTimeout(time.Second),
+		thttp.WithDeadlinePropagation(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := gin.New()
+	e.GET("/wait", Timeout(20*time.Millisecond), func(c *gin.Context) {
+		<-c.Request.Context().Done()
+	})
+	e.GET("/call", Timeout(100*time.Millisecond), func(c *gin.Context) {
+		var reply map[string]interface{}
+		if err := client.Get(c.Request.Context(), c.Query("path"), &reply); err != nil {
+			xgin.Failure(c, err)
+			return
+		}
+		xgin.Success(c)
+	})
+	e.GET("/fast", Timeout(time.Second), func(c *gin.Context) {
+		xgin.Success(c)
+	})
+
+	type testCase struct {
+		name   string
+		path   string
+		budget string
+		status int
+		code   int
+	}
+	testTable := []testCase{
+		{name: "handler overruns", path: "/wait", status: http.StatusGatewayTimeout, code: ecode.Code(ecode.Timeout)},
+		{name: "downstream timeout", path: "/call?path=/slow", status: http.StatusGatewayTimeout, code: ecode.Code(ecode.Timeout)},
+		{name: "downstream ok", path: "/call?path=/ok", status: http.StatusOK, code: 200},
+		{name: "client deadline", path: "/fast", budget: "0", status: http.StatusGatewayTimeout, code: ecode.Code(ecode.Timeout)},
+		{name: "fast", path: "/fast", budget: "500", status: http.StatusOK, code: 200},
+	}
+
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, v.path, nil)
+			if v.budget != "" {
+				req.Header.Set(deadline.Header, v.budget)
+			}
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, req)
+
+			if w.Code != v.status {
+				t.Errorf("expect status %d, but get %d", v.status, w.Code)
+			}
+			var resp struct {
+				Code int `json:"code"`
+			}
+			if err := encoding.GetCodec("json").Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Code != v.code {
+				t.Errorf("expect code %d, but get %s", v.code, w.Body.String())
+			}
+		})
+	}
+
+	// 下游收到的剩余时间不超过路由的超时时间减去预留的网络传输时间
+	mu.Lock()
+	defer mu.Unlock()
+	budget, ok := deadline.Parse(downstreamBudget)
+	if !ok || budget > 90*time.Millisecond {
+		t.Errorf("expect downstream budget <= 90ms, but get %q", downstreamBudget)
+	}
+}
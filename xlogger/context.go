@@ -0,0 +1,105 @@
+package xlogger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"skuld/requestid"
+)
+
+type fieldsKey struct{}
+
+// NewContext 将 kvs 追加到 ctx 中, 之后通过 WithContext(ctx, l) 输出的日志都会附带这些字段
+func NewContext(ctx context.Context, kvs ...interface{}) context.Context {
+	if len(kvs) == 0 {
+		return ctx
+	}
+	prev, _ := ctx.Value(fieldsKey{}).([]interface{})
+	fields := make([]interface{}, 0, len(prev)+len(kvs))
+	fields = append(fields, prev...)
+	fields = append(fields, kvs...)
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// FromContext 返回 ctx 中需要输出到日志的字段, 依次为请求 ID, 链路追踪的 trace_id / span_id 以及 NewContext 保存的字段
+func FromContext(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+	id := requestid.FromContext(ctx)
+	sc := trace.SpanContextFromContext(ctx)
+	if id == "" && !sc.IsValid() {
+		return fields
+	}
+
+	kvs := make([]interface{}, 0, len(fields)+6)
+	if id != "" {
+		kvs = append(kvs, "request_id", id)
+	}
+	if sc.IsValid() {
+		kvs = append(kvs, "trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+	}
+	return append(kvs, fields...)
+}
+
+// WithContext 返回一个会自动附带 ctx 中请求 ID 以及 NewContext 保存的字段的 Logger
+func WithContext(ctx context.Context, l Logger) Logger {
+	if cl, ok := l.(ContextLogger); ok {
+		return cl.WithContext(ctx)
+	}
+	return with(l, FromContext(ctx))
+}
+
+// with 返回附带 kvs 字段的 Logger
+func with(l Logger, kvs []interface{}) Logger {
+	if len(kvs) == 0 {
+		return l
+	}
+	if cl, ok := l.(ContextLogger); ok {
+		return cl.With(kvs...)
+	}
+	return &ctxLogger{logger: l, kvs: kvs}
+}
+
+// ctxLogger 为未实现 ContextLogger 的 Logger 附带字段
+type ctxLogger struct {
+	logger Logger
+	kvs    []interface{}
+}
+
+func (l *ctxLogger) with(kvs []interface{}) []interface{} {
+	return append(append(make([]interface{}, 0, len(l.kvs)+len(kvs)), l.kvs...), kvs...)
+}
+
+func (l *ctxLogger) Debug(msg string, kvs ...interface{}) {
+	l.logger.Debug(msg, l.with(kvs)...)
+}
+
+func (l *ctxLogger) Info(msg string, kvs ...interface{}) {
+	l.logger.Info(msg, l.with(kvs)...)
+}
+
+func (l *ctxLogger) Warn(msg string, kvs ...interface{}) {
+	l.logger.Warn(msg, l.with(kvs)...)
+}
+
+func (l *ctxLogger) Error(msg string, kvs ...interface{}) {
+	l.logger.Error(msg, l.with(kvs)...)
+}
+
+func (l *ctxLogger) Fatal(msg string, kvs ...interface{}) {
+	l.logger.Fatal(msg, l.with(kvs)...)
+}
+
+func (l *ctxLogger) With(kvs ...interface{}) Logger {
+	if len(kvs) == 0 {
+		return l
+	}
+	return &ctxLogger{logger: l.logger, kvs: l.with(kvs)}
+}
+
+func (l *ctxLogger) WithContext(ctx context.Context) Logger {
+	return l.With(FromContext(ctx)...)
+}
@@ -0,0 +1,38 @@
+package xlogger
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"skuld/requestid"
+)
+
+// plainLogger 只实现 Logger, 不实现 ContextLogger
+type plainLogger struct {
+	kvs *[]interface{}
+}
+
+func (l plainLogger) Debug(msg string, kvs ...interface{}) { *l.kvs = kvs }
+func (l plainLogger) Info(msg string, kvs ...interface{})  { *l.kvs = kvs }
+func (l plainLogger) Warn(msg string, kvs ...interface{})  { *l.kvs = kvs }
+func (l plainLogger) Error(msg string, kvs ...interface{}) { *l.kvs = kvs }
+func (l plainLogger) Fatal(msg string, kvs ...interface{}) { *l.kvs = kvs }
+
+func TestWithContext(t *testing.T) {
+	var got []interface{}
+	l := plainLogger{kvs: &got}
+
+	ctx := requestid.NewContext(context.Background(), "rid")
+	ctx = NewContext(ctx, "user", 1)
+	WithContext(ctx, l).Info("msg", "k", "v")
+
+	expect := []interface{}{"request_id", "rid", "user", 1, "k", "v"}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect %v, but get %v", expect, got)
+	}
+
+	if WithContext(context.Background(), l) != Logger(l) {
+		t.Fatal("logger without context fields should be returned as is")
+	}
+}
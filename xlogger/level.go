@@ -0,0 +1,179 @@
+package xlogger
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Levels 保存全局日志级别以及按模块 (Named 子 Logger 的名称) 覆盖的日志级别, 可以在运行时修改
+type Levels struct {
+	root    zap.AtomicLevel
+	mu      sync.Mutex
+	modules atomic.Value // map[string]zapcore.Level
+}
+
+// LevelsOf 返回 l 的可运行时修改的日志级别, l 不是 NewZap / NewZapLogger 创建的 Logger 时返回 nil,
+// 如 app.HandleAdmin("/debug/log/level", xlogger.LevelsOf(logger))
+func LevelsOf(l Logger) *Levels {
+	if z, ok := l.(*ZapLogger); ok {
+		return z.levels
+	}
+	return nil
+}
+
+func newLevels(l zapcore.Level) *Levels {
+	ls := &Levels{root: zap.NewAtomicLevelAt(l)}
+	ls.modules.Store(map[string]zapcore.Level{})
+	return ls
+}
+
+// Level 返回全局日志级别
+func (ls *Levels) Level() string {
+	return ls.root.Level().String()
+}
+
+// SetLevel 修改全局日志级别, level 为 debug / info / warn / error 等
+func (ls *Levels) SetLevel(level string) error {
+	l, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	ls.root.SetLevel(l)
+	return nil
+}
+
+// SetModuleLevel 修改模块的日志级别, level 为空时删除覆盖, 使用全局日志级别
+func (ls *Levels) SetModuleLevel(module, level string) error {
+	var (
+		l   zapcore.Level
+		err error
+	)
+	if level != "" {
+		if l, err = parseLevel(level); err != nil {
+			return err
+		}
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	prev := ls.modules.Load().(map[string]zapcore.Level)
+	next := make(map[string]zapcore.Level, len(prev)+1)
+	for k, v := range prev {
+		next[k] = v
+	}
+	if level == "" {
+		delete(next, module)
+	} else {
+		next[module] = l
+	}
+	ls.modules.Store(next)
+	return nil
+}
+
+// ModuleLevels 返回所有模块覆盖的日志级别
+func (ls *Levels) ModuleLevels() map[string]string {
+	modules := ls.modules.Load().(map[string]zapcore.Level)
+	m := make(map[string]string, len(modules))
+	for k, v := range modules {
+		m[k] = v.String()
+	}
+	return m
+}
+
+func (ls *Levels) enabler(module string) zapcore.LevelEnabler {
+	return moduleEnabler{levels: ls, module: module}
+}
+
+func (ls *Levels) enabled(module string, l zapcore.Level) bool {
+	if module != "" {
+		if ml, ok := ls.modules.Load().(map[string]zapcore.Level)[module]; ok {
+			return ml.Enabled(l)
+		}
+	}
+	return ls.root.Enabled(l)
+}
+
+type levelPayload struct {
+	Level   string            `json:"level"`
+	Module  string            `json:"module,omitempty"`
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+// ServeHTTP 查询或修改日志级别
+//
+//	GET  返回 {"level":"info","modules":{"xorm":"debug"}}
+//	PUT  {"level":"debug"} 修改全局日志级别
+//	PUT  {"module":"xorm","level":"debug"} 修改模块日志级别, level 为空时删除覆盖
+func (ls *Levels) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		req := levelPayload{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		var err error
+		if req.Module != "" {
+			err = ls.SetModuleLevel(req.Module, req.Level)
+		} else {
+			err = ls.SetLevel(req.Level)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "only GET and PUT are supported"})
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(levelPayload{Level: ls.Level(), Modules: ls.ModuleLevels()})
+}
+
+func parseLevel(level string) (zapcore.Level, error) {
+	var l zapcore.Level
+	err := l.UnmarshalText([]byte(level))
+	return l, err
+}
+
+type moduleEnabler struct {
+	levels *Levels
+	module string
+}
+
+func (e moduleEnabler) Enabled(l zapcore.Level) bool {
+	return e.levels.enabled(e.module, l)
+}
+
+// levelCore 使用 Levels 决定日志是否输出, 底层 core 本身不做级别过滤
+type levelCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+func (c levelCore) Enabled(l zapcore.Level) bool {
+	return c.enabler.Enabled(l)
+}
+
+func (c levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return levelCore{Core: c.Core.With(fields), enabler: c.enabler}
+}
+
+func (c levelCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabler.Enabled(e.Level) {
+		return ce
+	}
+	return c.Core.Check(e, ce)
+}
@@ -0,0 +1,148 @@
+package xlogger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLevels(t *testing.T) {
+	ls := newLevels(0)
+	if ls.Level() != "info" {
+		t.Fatalf("expect info, but get %s", ls.Level())
+	}
+	if err := ls.SetLevel("warn"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.SetLevel("bad"); err == nil {
+		t.Error("expect invalid level rejected")
+	}
+	if ls.Level() != "warn" {
+		t.Errorf("expect warn kept after invalid level, but get %s", ls.Level())
+	}
+
+	if err := ls.SetModuleLevel("xorm", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.SetModuleLevel("xorm", "bad"); err == nil {
+		t.Error("expect invalid module level rejected")
+	}
+	if m := ls.ModuleLevels(); !reflect.DeepEqual(m, map[string]string{"xorm": "debug"}) {
+		t.Errorf("unexpect module levels %v", m)
+	}
+
+	type testCase struct {
+		module string
+		level  string
+		expect bool
+	}
+	testTable := []testCase{
+		{module: "", level: "info", expect: false},
+		{module: "", level: "warn", expect: true},
+		{module: "xorm", level: "debug", expect: true},
+		{module: "other", level: "info", expect: false},
+	}
+	for _, v := range testTable {
+		l, _ := parseLevel(v.level)
+		if got := ls.enabler(v.module).Enabled(l); got != v.expect {
+			t.Errorf("expect module %q level %s enabled %v, but get %v", v.module, v.level, v.expect, got)
+		}
+	}
+
+	// level 为空时删除覆盖
+	if err := ls.SetModuleLevel("xorm", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(ls.ModuleLevels()) != 0 || ls.enabler("xorm").Enabled(-1) {
+		t.Error("expect module level removed")
+	}
+}
+
+func TestLevelsServeHTTP(t *testing.T) {
+	ls := newLevels(0)
+
+	do := func(method, body string) (int, levelPayload) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		ls.ServeHTTP(w, httptest.NewRequest(method, "/debug/log/level", strings.NewReader(body)))
+		var rst levelPayload
+		_ = json.Unmarshal(w.Body.Bytes(), &rst)
+		return w.Code, rst
+	}
+
+	if code, rst := do(http.MethodGet, ""); code != http.StatusOK || rst.Level != "info" {
+		t.Errorf("unexpect GET %d %+v", code, rst)
+	}
+	if code, rst := do(http.MethodPut, `{"level":"debug"}`); code != http.StatusOK || rst.Level != "debug" {
+		t.Errorf("unexpect PUT level %d %+v", code, rst)
+	}
+	if code, rst := do(http.MethodPut, `{"module":"xorm","level":"error"}`); code != http.StatusOK || rst.Modules["xorm"] != "error" {
+		t.Errorf("unexpect PUT module level %d %+v", code, rst)
+	}
+
+	type testCase struct {
+		name   string
+		method string
+		body   string
+		expect int
+	}
+	testTable := []testCase{
+		{name: "invalid level", method: http.MethodPut, body: `{"level":"bad"}`, expect: http.StatusBadRequest},
+		{name: "invalid module level", method: http.MethodPut, body: `{"module":"xorm","level":"bad"}`, expect: http.StatusBadRequest},
+		{name: "invalid json", method: http.MethodPut, body: `{`, expect: http.StatusBadRequest},
+		{name: "method not allowed", method: http.MethodDelete, expect: http.StatusMethodNotAllowed},
+	}
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			if code, _ := do(v.method, v.body); code != v.expect {
+				t.Errorf("expect status %d, but get %d", v.expect, code)
+			}
+		})
+	}
+	if ls.Level() != "debug" || ls.ModuleLevels()["xorm"] != "error" {
+		t.Errorf("expect levels unchanged by invalid requests, but get %s %v", ls.Level(), ls.ModuleLevels())
+	}
+}
+
+func TestNamed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	z, err := NewZap(WithOutputs(path), WithLevel("warn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orm := z.Named("db").Named("xorm")
+	if orm.module != "db.xorm" {
+		t.Fatalf("expect module db.xorm, but get %s", orm.module)
+	}
+	if LevelsOf(orm) != LevelsOf(z) {
+		t.Fatal("expect named logger share levels")
+	}
+	if err = z.Levels().SetModuleLevel("db.xorm", "debug"); err != nil {
+		t.Fatal(err)
+	}
+
+	z.Info("root info")
+	orm.Debug("orm debug")
+	// 未覆盖级别的模块使用全局日志级别
+	z.Named("db").Info("db info")
+	if err = z.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := string(data)
+	if !strings.Contains(log, `"logger":"db.xorm"`) || !strings.Contains(log, "orm debug") {
+		t.Errorf("expect debug log of db.xorm, but get %s", log)
+	}
+	if strings.Contains(log, "root info") || strings.Contains(log, "db info") {
+		t.Errorf("expect info logs filtered by root level, but get %s", log)
+	}
+}
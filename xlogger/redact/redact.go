@@ -0,0 +1,302 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Mode 敏感数据的处理方式
+type Mode int
+
+const (
+	// Mask 使用固定字符串替换
+	Mask Mode = iota
+	// Hash 使用以 WithHashKey 为密钥的 HMAC-SHA256 摘要替换, 相同的值得到相同的结果, 便于排查问题时关联;
+	// 使用密钥防止手机号等取值范围小的数据被穷举还原
+	Hash
+)
+
+type Option func(*Redactor)
+
+// WithHeaders 追加需要脱敏的 http 请求头 / 响应头, 不区分大小写
+func WithHeaders(names ...string) Option {
+	return func(r *Redactor) {
+		for _, name := range names {
+			r.headers[strings.ToLower(name)] = struct{}{}
+		}
+	}
+}
+
+// WithFields 追加需要脱敏的 JSON 字段路径, 不区分大小写
+//
+// 不含 "." 的路径匹配任意层级的同名字段, 如 password;
+// 含 "." 的路径从根开始匹配, 如 user.phone, 数组不占用路径层级, "*" 匹配任意字段名
+func WithFields(paths ...string) Option {
+	return func(r *Redactor) {
+		for _, path := range paths {
+			r.fields = append(r.fields, strings.Split(strings.ToLower(path), "."))
+		}
+	}
+}
+
+// WithPatterns 追加需要脱敏的正则表达式, 字符串中匹配的部分会被替换
+func WithPatterns(patterns ...*regexp.Regexp) Option {
+	return func(r *Redactor) {
+		r.patterns = append(r.patterns, patterns...)
+	}
+}
+
+// WithMode 设置脱敏方式, 默认 Mask
+func WithMode(mode Mode) Option {
+	return func(r *Redactor) {
+		r.mode = mode
+	}
+}
+
+// WithHashKey 设置 Hash 模式的 HMAC 密钥, 多个实例需要关联同一个值时配置相同的密钥;
+// 未设置时每个 Redactor 随机生成密钥, 只能在同一进程内关联
+func WithHashKey(key []byte) Option {
+	return func(r *Redactor) {
+		r.hashKey = key
+	}
+}
+
+// WithMask 设置 Mask 模式下的替换字符串, 默认 ******
+func WithMask(mask string) Option {
+	return func(r *Redactor) {
+		r.mask = mask
+	}
+}
+
+// Redactor 对日志中的请求头, JSON 字段和字符串进行脱敏
+type Redactor struct {
+	headers  map[string]struct{}
+	fields   [][]string
+	patterns []*regexp.Regexp
+	mode     Mode
+	mask     string
+	hashKey  []byte
+}
+
+func New(opts ...Option) *Redactor {
+	r := &Redactor{
+		headers: make(map[string]struct{}),
+		mode:    Mask,
+		mask:    "******",
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.mode == Hash && len(r.hashKey) == 0 {
+		r.hashKey = make([]byte, 32)
+		_, _ = rand.Read(r.hashKey)
+	}
+	return r
+}
+
+// DefaultHeaders 默认脱敏的请求头
+var DefaultHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// DefaultFields 默认脱敏的字段
+var DefaultFields = []string{"password", "passwd", "pwd", "secret", "token", "access_token", "refresh_token"}
+
+// DefaultPatterns 默认脱敏的正则, 匹配大陆手机号
+var DefaultPatterns = []*regexp.Regexp{regexp.MustCompile(`\b1[3-9]\d{9}\b`)}
+
+// Default 使用 DefaultHeaders, DefaultFields 和 DefaultPatterns 创建 Redactor, opts 可以追加规则
+func Default(opts ...Option) *Redactor {
+	return New(append([]Option{
+		WithHeaders(DefaultHeaders...),
+		WithFields(DefaultFields...),
+		WithPatterns(DefaultPatterns...),
+	}, opts...)...)
+}
+
+func (r *Redactor) replace(s string) string {
+	if r.mode == Hash {
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(s))
+		return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:8])
+	}
+	return r.mask
+}
+
+// String 替换字符串中匹配正则的部分
+func (r *Redactor) String(s string) string {
+	for _, p := range r.patterns {
+		s = p.ReplaceAllStringFunc(s, r.replace)
+	}
+	return s
+}
+
+// Header 返回脱敏后的 http 头拷贝
+func (r *Redactor) Header(h http.Header) http.Header {
+	if h == nil {
+		return nil
+	}
+	rst := make(http.Header, len(h))
+	for k, vs := range h {
+		_, sensitive := r.headers[strings.ToLower(k)]
+		cp := make([]string, len(vs))
+		for i, v := range vs {
+			if sensitive {
+				cp[i] = r.replace(v)
+			} else {
+				cp[i] = r.String(v)
+			}
+		}
+		rst[k] = cp
+	}
+	return rst
+}
+
+// JSON 对 JSON 数据脱敏, 无法解析时按字符串处理
+func (r *Redactor) JSON(data []byte) []byte {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return []byte(r.String(string(data)))
+	}
+	rst, err := json.Marshal(r.value(nil, v))
+	if err != nil {
+		return []byte(r.String(string(data)))
+	}
+	return rst
+}
+
+// Form 对 application/x-www-form-urlencoded 数据脱敏, 字段名按 WithFields 中不含 "." 的规则匹配, 无法解析时按字符串处理
+func (r *Redactor) Form(data []byte) []byte {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return []byte(r.String(string(data)))
+	}
+	for k, vs := range values {
+		sensitive := r.matchField([]string{strings.ToLower(k)})
+		for i, v := range vs {
+			if sensitive {
+				vs[i] = r.replace(v)
+			} else {
+				vs[i] = r.String(v)
+			}
+		}
+	}
+	return []byte(values.Encode())
+}
+
+// Value 返回脱敏后的值, 支持 json.Unmarshal 得到的 map / slice / string 以及 http.Header,
+// 其他类型原样返回, 不会修改传入的值
+func (r *Redactor) Value(v interface{}) interface{} {
+	return r.value(nil, v)
+}
+
+// KVs 对日志的 key / value 列表脱敏, key 与字段规则匹配时替换整个 value
+func (r *Redactor) KVs(kvs []interface{}) []interface{} {
+	if len(kvs) == 0 {
+		return kvs
+	}
+	rst := make([]interface{}, len(kvs))
+	for i := 0; i < len(kvs); i += 2 {
+		rst[i] = kvs[i]
+		if i+1 >= len(kvs) {
+			break
+		}
+		if key, ok := kvs[i].(string); ok && r.matchField([]string{strings.ToLower(key)}) {
+			rst[i+1] = r.sensitive(kvs[i+1])
+			continue
+		}
+		rst[i+1] = r.value(nil, kvs[i+1])
+	}
+	return rst
+}
+
+func (r *Redactor) value(path []string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return r.String(val)
+	case map[string]interface{}:
+		rst := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			p := appendPath(path, k)
+			if r.matchField(p) {
+				rst[k] = r.sensitive(item)
+				continue
+			}
+			rst[k] = r.value(p, item)
+		}
+		return rst
+	case map[string]string:
+		rst := make(map[string]string, len(val))
+		for k, item := range val {
+			p := appendPath(path, k)
+			if r.matchField(p) {
+				rst[k] = r.replace(item)
+				continue
+			}
+			rst[k] = r.String(item)
+		}
+		return rst
+	case []interface{}:
+		rst := make([]interface{}, len(val))
+		for i, item := range val {
+			rst[i] = r.value(path, item)
+		}
+		return rst
+	case http.Header:
+		return r.Header(val)
+	default:
+		return v
+	}
+}
+
+func (r *Redactor) sensitive(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return r.replace(val)
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return r.mask
+		}
+		return r.replace(string(data))
+	}
+}
+
+func (r *Redactor) matchField(path []string) bool {
+	for _, rule := range r.fields {
+		if len(rule) == 1 {
+			if rule[0] == path[len(path)-1] {
+				return true
+			}
+			continue
+		}
+		if len(rule) != len(path) {
+			continue
+		}
+		matched := true
+		for i := range rule {
+			if rule[i] != "*" && rule[i] != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func appendPath(path []string, key string) []string {
+	p := make([]string, len(path)+1)
+	copy(p, path)
+	p[len(path)] = strings.ToLower(key)
+	return p
+}
@@ -0,0 +1,94 @@
+package redact
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	type testCase struct {
+		name   string
+		r      *Redactor
+		input  interface{}
+		expect interface{}
+	}
+
+	r := Default(WithFields("user.id_card"))
+
+	testTable := []testCase{
+		{
+			name:   "field at any depth",
+			r:      r,
+			input:  map[string]interface{}{"a": map[string]interface{}{"Password": "123"}},
+			expect: map[string]interface{}{"a": map[string]interface{}{"Password": "******"}},
+		},
+		{
+			name:   "dotted path from root",
+			r:      r,
+			input:  map[string]interface{}{"user": map[string]interface{}{"id_card": "x"}, "id_card": "y"},
+			expect: map[string]interface{}{"user": map[string]interface{}{"id_card": "******"}, "id_card": "y"},
+		},
+		{
+			name:   "array is transparent",
+			r:      r,
+			input:  map[string]interface{}{"user": []interface{}{map[string]interface{}{"id_card": "x"}}},
+			expect: map[string]interface{}{"user": []interface{}{map[string]interface{}{"id_card": "******"}}},
+		},
+		{
+			name:   "pattern in string",
+			r:      r,
+			input:  "call 13812345678 now",
+			expect: "call ****** now",
+		},
+		{
+			name:   "header",
+			r:      r,
+			input:  http.Header{"Authorization": {"Bearer x"}, "Accept": {"*/*"}},
+			expect: http.Header{"Authorization": {"******"}, "Accept": {"*/*"}},
+		},
+		{
+			name:   "hash mode",
+			r:      New(WithPatterns(regexp.MustCompile(`secret`)), WithMode(Hash), WithHashKey([]byte("key"))),
+			input:  "secret",
+			expect: "hmac:25cf3c44c8f39313",
+		},
+	}
+
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			rst := v.r.Value(v.input)
+			if !reflect.DeepEqual(rst, v.expect) {
+				t.Fatalf("expect %v, but get %v", v.expect, rst)
+			}
+		})
+	}
+}
+
+func TestRedactorKVs(t *testing.T) {
+	r := Default()
+	rst := r.KVs([]interface{}{"token", "abc", "phone", "13812345678", "n", 1})
+	expect := []interface{}{"token", "******", "phone", "******", "n", 1}
+	if !reflect.DeepEqual(rst, expect) {
+		t.Fatalf("expect %v, but get %v", expect, rst)
+	}
+}
+
+func TestRedactorForm(t *testing.T) {
+	r := Default()
+	rst := string(r.Form([]byte("username=tom&password=123456&phone=13812345678")))
+	expect := "password=%2A%2A%2A%2A%2A%2A&phone=%2A%2A%2A%2A%2A%2A&username=tom"
+	if rst != expect {
+		t.Fatalf("expect %s, but get %s", expect, rst)
+	}
+}
+
+func TestRedactorHashKey(t *testing.T) {
+	a := New(WithFields("phone"), WithMode(Hash))
+	b := New(WithFields("phone"), WithMode(Hash))
+	// 未配置密钥时随机生成, 不同实例的结果不同, 无法通过预先计算的摘要还原
+	if reflect.DeepEqual(a.KVs([]interface{}{"phone", "13812345678"}), b.KVs([]interface{}{"phone", "13812345678"})) {
+		t.Fatalf("expect different digests for random keys")
+	}
+}
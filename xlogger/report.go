@@ -0,0 +1,62 @@
+package xlogger
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"skuld/xlogger/report"
+)
+
+// reportCore 将 Error 及以上级别的日志转发给 report.Reporter, 本身不输出日志
+type reportCore struct {
+	zapcore.Core
+	reporter report.Reporter
+	fields   []zapcore.Field
+}
+
+func (c reportCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	all = append(all, fields...)
+	return reportCore{Core: c.Core.With(fields), reporter: c.reporter, fields: all}
+}
+
+func (c reportCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= zapcore.ErrorLevel {
+		ce = ce.AddCore(ent, c)
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c reportCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	if ent.LoggerName != "" {
+		enc.Fields["logger"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		enc.Fields["caller"] = ent.Caller.String()
+	}
+
+	c.reporter.Report(report.Event{
+		Level:   ent.Level.String(),
+		Message: ent.Message,
+		Fields:  enc.Fields,
+		Stack:   ent.Stack,
+		Time:    ent.Time,
+	})
+
+	// Fatal 写入后进程退出, 等待上报完成
+	if ent.Level >= zapcore.FatalLevel {
+		if f, ok := c.reporter.(report.Flusher); ok {
+			f.Flush(3 * time.Second)
+		}
+	}
+	return nil
+}
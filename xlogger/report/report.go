@@ -0,0 +1,158 @@
+// Package report 将 Error / Fatal 日志以及 panic 转发到错误追踪服务
+package report
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event 一次需要上报的错误
+type Event struct {
+	Level       string                 `json:"level"`
+	Message     string                 `json:"message"`
+	Fields      map[string]interface{} `json:"fields,omitempty"`
+	Stack       string                 `json:"stack,omitempty"`
+	Fingerprint string                 `json:"fingerprint"`
+	Time        time.Time              `json:"time"`
+	// Count 去重窗口内相同 Fingerprint 的错误次数
+	Count int `json:"count"`
+}
+
+// Reporter 错误上报接口, Report 不应阻塞调用方
+type Reporter interface {
+	Report(e Event)
+}
+
+// Flusher 可选接口, 进程退出前 (如 Fatal) 等待已提交的错误上报完成
+type Flusher interface {
+	Flush(timeout time.Duration)
+}
+
+// Fingerprint 根据级别, 消息和调用栈中的函数名计算指纹, 忽略行号使代码小幅变动后指纹不变
+func Fingerprint(e Event) string {
+	h := sha1.New()
+	h.Write([]byte(e.Level))
+	h.Write([]byte{0})
+	h.Write([]byte(e.Message))
+	for _, line := range strings.Split(e.Stack, "\n") {
+		// debug.Stack 和 zap 的调用栈中, 函数名所在行不以制表符开头
+		if line != "" && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "goroutine ") {
+			h.Write([]byte{0})
+			h.Write([]byte(line))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type LimitOption func(*limiter)
+
+// WithDedupWindow 相同指纹的错误在 window 内只上报一次, 下一次上报时 Count 为累计次数, 默认 1 分钟
+func WithDedupWindow(window time.Duration) LimitOption {
+	return func(l *limiter) {
+		l.window = window
+	}
+}
+
+// WithRateLimit 每 per 时间内最多上报 n 个错误, 超出的错误被丢弃, 默认每分钟 60 个
+func WithRateLimit(n int, per time.Duration) LimitOption {
+	return func(l *limiter) {
+		l.limit = n
+		l.per = per
+	}
+}
+
+type seen struct {
+	last  time.Time
+	count int
+}
+
+// limiter 对 Reporter 去重和限流
+type limiter struct {
+	reporter Reporter
+	window   time.Duration
+	limit    int
+	per      time.Duration
+
+	mu      sync.Mutex
+	seen    map[string]*seen
+	start   time.Time
+	reports int
+}
+
+// Limit 返回对 r 去重和限流后的 Reporter
+func Limit(r Reporter, opts ...LimitOption) Reporter {
+	l := &limiter{
+		reporter: r,
+		window:   time.Minute,
+		limit:    60,
+		per:      time.Minute,
+		seen:     make(map[string]*seen),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+func (l *limiter) Report(e Event) {
+	if e.Fingerprint == "" {
+		e.Fingerprint = Fingerprint(e)
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	if !l.allow(&e) {
+		return
+	}
+	l.reporter.Report(e)
+}
+
+func (l *limiter) allow(e *Event) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := e.Time
+	s, ok := l.seen[e.Fingerprint]
+	if !ok {
+		s = &seen{}
+		l.seen[e.Fingerprint] = s
+	}
+	s.count++
+	if ok && now.Sub(s.last) < l.window {
+		return false
+	}
+
+	if now.Sub(l.start) >= l.per {
+		l.start = now
+		l.reports = 0
+		l.cleanup(now)
+	}
+	if l.limit > 0 && l.reports >= l.limit {
+		return false
+	}
+	l.reports++
+
+	e.Count = s.count
+	s.count = 0
+	s.last = now
+	return true
+}
+
+// cleanup 删除超过去重窗口且没有累计次数的指纹, 防止 map 无限增长
+func (l *limiter) cleanup(now time.Time) {
+	for k, s := range l.seen {
+		if s.count == 0 && now.Sub(s.last) >= l.window {
+			delete(l.seen, k)
+		}
+	}
+}
+
+func (l *limiter) Flush(timeout time.Duration) {
+	if f, ok := l.reporter.(Flusher); ok {
+		f.Flush(timeout)
+	}
+}
@@ -0,0 +1,189 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+type WebhookOption func(*Webhook)
+
+// WithHTTPClient 设置发送请求的 http.Client, 默认超时 5 秒
+func WithHTTPClient(client *http.Client) WebhookOption {
+	return func(w *Webhook) {
+		w.client = client
+	}
+}
+
+// WithHeader 设置请求头, 如鉴权 token
+func WithHeader(key, value string) WebhookOption {
+	return func(w *Webhook) {
+		w.header.Set(key, value)
+	}
+}
+
+// WithQueueSize 设置等待发送的错误数量上限, 队列满时丢弃新的错误, 默认 256
+func WithQueueSize(n int) WebhookOption {
+	return func(w *Webhook) {
+		w.size = n
+	}
+}
+
+// WithErrorHandler 设置发送失败时的回调, 默认输出到 stderr
+//
+// 回调中不要使用设置了该 Reporter 的 Logger 输出 Error 日志, 否则发送失败时会循环上报
+func WithErrorHandler(f func(err error)) WebhookOption {
+	return func(w *Webhook) {
+		w.onError = f
+	}
+}
+
+// Webhook 将错误以 json 格式 POST 到指定的 url, 发送在后台协程中进行, Report 不会阻塞
+type Webhook struct {
+	url     string
+	client  *http.Client
+	header  http.Header
+	size    int
+	onError func(err error)
+
+	queue  chan Event
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+
+	// pending 为已入队未发送完成的错误数量, 归零时关闭 idle 中的 channel 通知 Flush
+	pendingMu sync.Mutex
+	pending   int
+	idle      []chan struct{}
+}
+
+func NewWebhook(url string, opts ...WebhookOption) *Webhook {
+	w := &Webhook{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+		header: make(http.Header),
+		size:   256,
+		onError: func(err error) {
+			fmt.Fprintf(os.Stderr, "report webhook: %v\n", err)
+		},
+		done: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.queue = make(chan Event, w.size)
+	go w.run()
+	return w
+}
+
+func (w *Webhook) Report(e Event) {
+	if e.Fingerprint == "" {
+		e.Fingerprint = Fingerprint(e)
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Count == 0 {
+		e.Count = 1
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return
+	}
+	w.addPending(1)
+	select {
+	case w.queue <- e:
+	default:
+		w.addPending(-1)
+		w.onError(fmt.Errorf("queue is full, drop %s", e.Fingerprint))
+	}
+}
+
+func (w *Webhook) addPending(delta int) {
+	w.pendingMu.Lock()
+	defer w.pendingMu.Unlock()
+	w.pending += delta
+	if w.pending > 0 {
+		return
+	}
+	for _, ch := range w.idle {
+		close(ch)
+	}
+	w.idle = nil
+}
+
+func (w *Webhook) run() {
+	defer close(w.done)
+	for e := range w.queue {
+		if err := w.send(e); err != nil {
+			w.onError(err)
+		}
+		w.addPending(-1)
+	}
+}
+
+func (w *Webhook) send(e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range w.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Flush 等待队列中的错误发送完成, 最多等待 timeout
+func (w *Webhook) Flush(timeout time.Duration) {
+	w.pendingMu.Lock()
+	if w.pending == 0 {
+		w.pendingMu.Unlock()
+		return
+	}
+	ch := make(chan struct{})
+	w.idle = append(w.idle, ch)
+	w.pendingMu.Unlock()
+
+	select {
+	case <-ch:
+	case <-time.After(timeout):
+	}
+}
+
+// Close 停止接收新的错误, 并等待队列中的错误发送完成
+func (w *Webhook) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
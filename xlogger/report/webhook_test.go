@@ -0,0 +1,117 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []Event
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var e Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	w := NewWebhook(srv.URL, WithHeader("X-Token", "token"), WithErrorHandler(func(err error) {
+		t.Errorf("send error: %v", err)
+	}))
+	r := Limit(w, WithDedupWindow(time.Hour), WithRateLimit(2, time.Hour))
+
+	now := time.Now()
+	stack := "main.handler()\n\t/app/main.go:10\n"
+	r.Report(Event{Level: "error", Message: "db error", Stack: stack, Time: now})
+	// 相同指纹在去重窗口内不发送
+	r.Report(Event{Level: "error", Message: "db error", Stack: stack, Time: now.Add(time.Second)})
+	r.Report(Event{Level: "error", Message: "redis error", Time: now})
+	// 超过限流
+	r.Report(Event{Level: "error", Message: "mq error", Time: now})
+	// 去重窗口过后发送, Count 包含窗口内的次数
+	r.Report(Event{Level: "error", Message: "db error", Stack: "main.handler()\n\t/app/main.go:12\n", Time: now.Add(2 * time.Hour)})
+
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 3 {
+		t.Fatalf("expect 3 events, but get %d: %+v", len(events), events)
+	}
+	if events[0].Message != "db error" || events[0].Count != 1 || events[0].Stack != stack {
+		t.Errorf("unexpected first event: %+v", events[0])
+	}
+	if events[1].Message != "redis error" {
+		t.Errorf("unexpected second event: %+v", events[1])
+	}
+	if events[2].Fingerprint != events[0].Fingerprint || events[2].Count != 2 {
+		t.Errorf("unexpected third event: %+v", events[2])
+	}
+}
+
+func TestWebhookFlush(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		sent int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent++
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	w := NewWebhook(srv.URL, WithQueueSize(1024))
+	defer w.Close(context.Background())
+
+	// Report 与 Flush 并发调用
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				w.Report(Event{Level: "error", Message: "err"})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				w.Flush(time.Second)
+			}
+		}()
+	}
+	wg.Wait()
+
+	w.Flush(5 * time.Second)
+	mu.Lock()
+	defer mu.Unlock()
+	if sent != 200 {
+		t.Errorf("expect 200 events sent after flush, but get %d", sent)
+	}
+
+	// 没有等待发送的错误时立即返回
+	start := time.Now()
+	w.Flush(time.Second)
+	if time.Since(start) > 100*time.Millisecond {
+		t.Error("expect flush return immediately when idle")
+	}
+}
@@ -0,0 +1,158 @@
+package xlogger
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingRule 每个周期内相同级别相同内容的日志, 输出前 First 条, 之后每 Thereafter 条输出一条
+type SamplingRule struct {
+	First      int `mapstructure:"first"`
+	Thereafter int `mapstructure:"thereafter"`
+}
+
+// Sampling 日志采样配置, 未配置规则的级别不采样
+type Sampling struct {
+	// Interval 采样周期, 每个周期结束时输出被丢弃日志的汇总, 默认 1 秒
+	Interval time.Duration `mapstructure:"interval"`
+	// Rules 按级别配置的采样规则, key 为 debug / info / warn / error
+	Rules map[string]SamplingRule `mapstructure:"rules"`
+}
+
+type samplingKey struct {
+	level zapcore.Level
+	msg   string
+}
+
+// sampler 按周期统计日志数量并决定是否输出, 被 sampleCore 共享
+type sampler struct {
+	interval time.Duration
+	rules    map[zapcore.Level]SamplingRule
+	// out 输出 Debug / Info / Warn 日志的汇总, errOut 输出 Error 及以上级别日志的汇总
+	out    zapcore.Core
+	errOut zapcore.Core
+
+	mu      sync.Mutex
+	counts  map[samplingKey]int
+	dropped map[samplingKey]int
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newSampler(s Sampling, out, errOut zapcore.Core) (*sampler, error) {
+	rules := make(map[zapcore.Level]SamplingRule, len(s.Rules))
+	for name, rule := range s.Rules {
+		l, err := parseLevel(name)
+		if err != nil {
+			return nil, err
+		}
+		rules[l] = rule
+	}
+	if s.Interval <= 0 {
+		s.Interval = time.Second
+	}
+
+	sp := &sampler{
+		interval: s.Interval,
+		rules:    rules,
+		out:      out,
+		errOut:   errOut,
+		counts:   make(map[samplingKey]int),
+		dropped:  make(map[samplingKey]int),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go sp.run()
+	return sp, nil
+}
+
+func (s *sampler) allow(ent zapcore.Entry) bool {
+	rule, ok := s.rules[ent.Level]
+	if !ok {
+		return true
+	}
+
+	key := samplingKey{level: ent.Level, msg: ent.Message}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts[key]++
+	n := s.counts[key]
+	if n <= rule.First || (rule.Thereafter > 0 && (n-rule.First)%rule.Thereafter == 0) {
+		return true
+	}
+	s.dropped[key]++
+	return false
+}
+
+func (s *sampler) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			s.flush()
+			return
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+// flush 开始新的采样周期, 并输出上一个周期被丢弃日志的汇总
+func (s *sampler) flush() {
+	s.mu.Lock()
+	dropped := s.dropped
+	s.counts = make(map[samplingKey]int, len(s.counts))
+	s.dropped = make(map[samplingKey]int)
+	s.mu.Unlock()
+
+	for key, n := range dropped {
+		ent := zapcore.Entry{
+			Level:   zapcore.WarnLevel,
+			Time:    time.Now(),
+			Message: "log messages dropped by sampling",
+		}
+		out := s.out
+		if key.level >= zapcore.ErrorLevel {
+			out = s.errOut
+		}
+		if ce := out.Check(ent, nil); ce != nil {
+			ce.Write(
+				zap.String("sampled_level", key.level.String()),
+				zap.String("sampled_msg", key.msg),
+				zap.Int("dropped", n),
+				zap.Duration("interval", s.interval),
+			)
+		}
+	}
+}
+
+// close 停止采样周期, 并等待最后一个周期的汇总输出完成
+func (s *sampler) close() {
+	s.once.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+// sampleCore 在输出前根据 sampler 丢弃重复日志
+type sampleCore struct {
+	zapcore.Core
+	sampler *sampler
+}
+
+func (c sampleCore) With(fields []zapcore.Field) zapcore.Core {
+	return sampleCore{Core: c.Core.With(fields), sampler: c.sampler}
+}
+
+func (c sampleCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Core.Enabled(ent.Level) || !c.sampler.allow(ent) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,171 @@
+package xlogger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"skuld/xlogger/redact"
+)
+
+func TestSamplerAllow(t *testing.T) {
+	out, logs := observer.New(zapcore.DebugLevel)
+	s, err := newSampler(Sampling{
+		Interval: time.Hour,
+		Rules:    map[string]SamplingRule{"info": {First: 2, Thereafter: 3}},
+	}, out, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.close()
+
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Message: "msg"}
+	var allowed []int
+	for i := 1; i <= 10; i++ {
+		if s.allow(ent) {
+			allowed = append(allowed, i)
+		}
+	}
+	// 前 2 条全部输出, 之后每 3 条输出一条
+	expect := []int{1, 2, 5, 8}
+	if len(allowed) != len(expect) {
+		t.Fatalf("expect allowed %v, but get %v", expect, allowed)
+	}
+	for i := range expect {
+		if allowed[i] != expect[i] {
+			t.Fatalf("expect allowed %v, but get %v", expect, allowed)
+		}
+	}
+
+	// 未配置规则的级别和不同内容的日志互不影响
+	if !s.allow(zapcore.Entry{Level: zapcore.WarnLevel, Message: "msg"}) {
+		t.Error("expect level without rule not sampled")
+	}
+	if !s.allow(zapcore.Entry{Level: zapcore.InfoLevel, Message: "other"}) {
+		t.Error("expect first entry of other msg allowed")
+	}
+
+	// 新周期重新计数, 并输出上一个周期的丢弃数量
+	s.flush()
+	if !s.allow(ent) || !s.allow(ent) || s.allow(ent) {
+		t.Error("expect counts reset after flush")
+	}
+	entries := logs.FilterMessage("log messages dropped by sampling").All()
+	if len(entries) != 1 {
+		t.Fatalf("expect 1 summary, but get %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["sampled_msg"] != "msg" || fields["sampled_level"] != "info" || fields["dropped"] != int64(6) {
+		t.Errorf("unexpect summary fields %v", fields)
+	}
+}
+
+func TestSamplerThereafterZero(t *testing.T) {
+	out, _ := observer.New(zapcore.DebugLevel)
+	s, err := newSampler(Sampling{
+		Interval: time.Hour,
+		Rules:    map[string]SamplingRule{"info": {First: 1}},
+	}, out, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.close()
+
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Message: "msg"}
+	if !s.allow(ent) {
+		t.Fatal("expect first entry allowed")
+	}
+	for i := 0; i < 5; i++ {
+		if s.allow(ent) {
+			t.Fatal("expect entries after first dropped when thereafter is 0")
+		}
+	}
+}
+
+func TestSamplerErrorSummary(t *testing.T) {
+	out, outLogs := observer.New(zapcore.DebugLevel)
+	errOut, errLogs := observer.New(zapcore.DebugLevel)
+	s, err := newSampler(Sampling{
+		Interval: time.Hour,
+		Rules:    map[string]SamplingRule{"info": {First: 1}, "error": {First: 1}},
+	}, out, errOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		s.allow(zapcore.Entry{Level: zapcore.InfoLevel, Message: "info"})
+		s.allow(zapcore.Entry{Level: zapcore.ErrorLevel, Message: "error"})
+	}
+	// close 返回时汇总已经输出
+	s.close()
+
+	// Error 日志的汇总输出到 errOut
+	if entries := errLogs.All(); len(entries) != 1 || entries[0].ContextMap()["sampled_msg"] != "error" {
+		t.Errorf("expect error summary in errOut, but get %v", entries)
+	}
+	if entries := outLogs.All(); len(entries) != 1 || entries[0].ContextMap()["sampled_msg"] != "info" {
+		t.Errorf("expect info summary in out, but get %v", entries)
+	}
+}
+
+// countMarshaler 记录被序列化的次数, 用于判断是否被脱敏
+type countMarshaler struct {
+	n *int32
+}
+
+func (c countMarshaler) MarshalJSON() ([]byte, error) {
+	atomic.AddInt32(c.n, 1)
+	return []byte(`"secret"`), nil
+}
+
+func TestZapSamplingBeforeRedact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	z, err := NewZap(
+		WithOutputs(path),
+		WithRedactor(redact.Default()),
+		WithSampling(Sampling{
+			Interval: time.Hour,
+			Rules:    map[string]SamplingRule{"info": {First: 2, Thereafter: 3}},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var redacted int32
+	for i := 0; i < 10; i++ {
+		z.Info("login", "password", countMarshaler{n: &redacted})
+	}
+	if err = z.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 被采样丢弃的日志不脱敏
+	if n := atomic.LoadInt32(&redacted); n != 4 {
+		t.Errorf("expect 4 entries redacted, but get %d", n)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(data, []byte(`"msg":"login"`)); n != 4 {
+		t.Errorf("expect 4 entries written, but get %d", n)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Errorf("expect password redacted, but get %s", data)
+	}
+	if !bytes.Contains(data, []byte(`"caller":"xlogger/sampling_test.go:`)) {
+		t.Errorf("expect caller of Info, but get %s", data)
+	}
+	// Close 等待最后一个周期的汇总写入后再关闭文件
+	if !bytes.Contains(data, []byte(`"dropped":6`)) {
+		t.Errorf("expect dropped summary written before close, but get %s", data)
+	}
+}
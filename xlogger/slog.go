@@ -0,0 +1,182 @@
+package xlogger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// slogHandler 将 log/slog 的日志写入 Logger, group 以 "." 连接作为 key 的前缀
+type slogHandler struct {
+	logger Logger
+	prefix string
+}
+
+// NewSlogHandler 返回写入 l 的 slog.Handler, 使用 slog 的第三方库可以通过 slog.New(NewSlogHandler(l)) 接入
+//
+// l 为 *ZapLogger 时保留 slog 记录的调用位置, 并根据其日志级别实现 Enabled
+func NewSlogHandler(l Logger) slog.Handler {
+	return &slogHandler{logger: l}
+}
+
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if z, ok := h.logger.(*ZapLogger); ok {
+		return z.enabled(zapLevel(level))
+	}
+	return true
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	kvs := make([]interface{}, 0, r.NumAttrs()*2)
+	r.Attrs(func(a slog.Attr) bool {
+		kvs = appendAttr(kvs, h.prefix, a)
+		return true
+	})
+
+	l := h.logger
+	if ctx != nil {
+		l = WithContext(ctx, l)
+	}
+
+	if z, ok := l.(*ZapLogger); ok {
+		z.write(zapLevel(r.Level), r.Message, r.PC, kvs)
+		return nil
+	}
+	switch {
+	case r.Level >= slog.LevelError:
+		l.Error(r.Message, kvs...)
+	case r.Level >= slog.LevelWarn:
+		l.Warn(r.Message, kvs...)
+	case r.Level >= slog.LevelInfo:
+		l.Info(r.Message, kvs...)
+	default:
+		l.Debug(r.Message, kvs...)
+	}
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	kvs := make([]interface{}, 0, len(attrs)*2)
+	for _, a := range attrs {
+		kvs = appendAttr(kvs, h.prefix, a)
+	}
+	return &slogHandler{logger: with(h.logger, kvs), prefix: h.prefix}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, prefix: h.prefix + name + "."}
+}
+
+func appendAttr(kvs []interface{}, prefix string, a slog.Attr) []interface{} {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix = prefix + a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			kvs = appendAttr(kvs, prefix, ga)
+		}
+		return kvs
+	}
+	if a.Key == "" {
+		return kvs
+	}
+	return append(kvs, prefix+a.Key, a.Value.Any())
+}
+
+func zapLevel(l slog.Level) zapcore.Level {
+	switch {
+	case l >= slog.LevelError:
+		return zapcore.ErrorLevel
+	case l >= slog.LevelWarn:
+		return zapcore.WarnLevel
+	case l >= slog.LevelInfo:
+		return zapcore.InfoLevel
+	default:
+		return zapcore.DebugLevel
+	}
+}
+
+func (z *ZapLogger) enabled(l zapcore.Level) bool {
+	if l >= zapcore.ErrorLevel {
+		return z.stderr.Desugar().Core().Enabled(l)
+	}
+	return z.stdout.Desugar().Core().Enabled(l)
+}
+
+// Slog 返回写入 z 的 *slog.Logger
+func (z *ZapLogger) Slog() *slog.Logger {
+	return slog.New(NewSlogHandler(z))
+}
+
+// slogLogger 将 Logger 的日志写入 *slog.Logger
+type slogLogger struct {
+	logger *slog.Logger
+	ctx    context.Context
+}
+
+// FromSlog 返回写入 l 的 Logger, Fatal 以 ERROR+4 级别输出后退出进程
+func FromSlog(l *slog.Logger) Logger {
+	return &slogLogger{logger: l, ctx: context.Background()}
+}
+
+// LevelFatal Fatal 日志对应的 slog 级别
+const LevelFatal = slog.LevelError + 4
+
+func (s *slogLogger) log(level slog.Level, msg string, kvs []interface{}) {
+	if !s.logger.Enabled(s.ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	// 跳过 runtime.Callers, log 以及 Debug 等方法
+	runtime.Callers(3, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(kvs...)
+	_ = s.logger.Handler().Handle(s.ctx, r)
+}
+
+func (s *slogLogger) Debug(msg string, kvs ...interface{}) {
+	s.log(slog.LevelDebug, msg, kvs)
+}
+
+func (s *slogLogger) Info(msg string, kvs ...interface{}) {
+	s.log(slog.LevelInfo, msg, kvs)
+}
+
+func (s *slogLogger) Warn(msg string, kvs ...interface{}) {
+	s.log(slog.LevelWarn, msg, kvs)
+}
+
+func (s *slogLogger) Error(msg string, kvs ...interface{}) {
+	s.log(slog.LevelError, msg, kvs)
+}
+
+func (s *slogLogger) Fatal(msg string, kvs ...interface{}) {
+	s.log(LevelFatal, msg, kvs)
+	os.Exit(1)
+}
+
+func (s *slogLogger) With(kvs ...interface{}) Logger {
+	if len(kvs) == 0 {
+		return s
+	}
+	return &slogLogger{logger: s.logger.With(kvs...), ctx: s.ctx}
+}
+
+// WithContext 将 ctx 传给 slog.Handler, 对于非 NewSlogHandler 创建的 handler, 同时将 ctx 中的字段作为属性附带
+func (s *slogLogger) WithContext(ctx context.Context) Logger {
+	l := s.logger
+	if _, ok := l.Handler().(*slogHandler); !ok {
+		if kvs := FromContext(ctx); len(kvs) > 0 {
+			l = l.With(kvs...)
+		}
+	}
+	return &slogLogger{logger: l, ctx: ctx}
+}
@@ -0,0 +1,150 @@
+package xlogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"skuld/requestid"
+)
+
+type recordEntry struct {
+	level string
+	msg   string
+	kvs   []interface{}
+}
+
+// recordLogger 记录日志的级别, 内容和 kvs, 不实现 ContextLogger
+type recordLogger struct {
+	entries *[]recordEntry
+}
+
+func (l recordLogger) add(level, msg string, kvs []interface{}) {
+	*l.entries = append(*l.entries, recordEntry{level: level, msg: msg, kvs: kvs})
+}
+
+func (l recordLogger) Debug(msg string, kvs ...interface{}) { l.add("debug", msg, kvs) }
+func (l recordLogger) Info(msg string, kvs ...interface{})  { l.add("info", msg, kvs) }
+func (l recordLogger) Warn(msg string, kvs ...interface{})  { l.add("warn", msg, kvs) }
+func (l recordLogger) Error(msg string, kvs ...interface{}) { l.add("error", msg, kvs) }
+func (l recordLogger) Fatal(msg string, kvs ...interface{}) { l.add("fatal", msg, kvs) }
+
+func TestSlogHandlerGroup(t *testing.T) {
+	var entries []recordEntry
+	l := slog.New(NewSlogHandler(recordLogger{entries: &entries}))
+
+	l.With("a", 1).WithGroup("g").With("b", 2).WithGroup("h").Info("msg",
+		"c", 3, slog.Group("i", "d", 4), slog.Group("", "e", 5), slog.Group("empty"))
+
+	expect := []interface{}{"a", int64(1), "g.b", int64(2), "g.h.c", int64(3), "g.h.i.d", int64(4), "g.h.e", int64(5)}
+	if len(entries) != 1 || !reflect.DeepEqual(entries[0].kvs, expect) {
+		t.Fatalf("expect %v, but get %v", expect, entries)
+	}
+
+	// WithGroup("") 不改变前缀
+	entries = nil
+	l.WithGroup("").Info("msg", "k", "v")
+	if expect = []interface{}{"k", "v"}; !reflect.DeepEqual(entries[0].kvs, expect) {
+		t.Errorf("expect %v, but get %v", expect, entries[0].kvs)
+	}
+}
+
+func TestSlogHandlerLevel(t *testing.T) {
+	var entries []recordEntry
+	l := slog.New(NewSlogHandler(recordLogger{entries: &entries}))
+
+	type testCase struct {
+		level  slog.Level
+		expect string
+	}
+	testTable := []testCase{
+		{level: slog.LevelDebug - 4, expect: "debug"},
+		{level: slog.LevelDebug, expect: "debug"},
+		{level: slog.LevelInfo, expect: "info"},
+		{level: slog.LevelInfo + 2, expect: "info"},
+		{level: slog.LevelWarn, expect: "warn"},
+		{level: slog.LevelError, expect: "error"},
+		{level: LevelFatal, expect: "error"},
+	}
+	for _, v := range testTable {
+		entries = nil
+		l.Log(context.Background(), v.level, "msg")
+		if len(entries) != 1 || entries[0].level != v.expect {
+			t.Errorf("expect slog level %v mapped to %s, but get %v", v.level, v.expect, entries)
+		}
+		if zl := zapLevel(v.level).String(); zl != v.expect {
+			t.Errorf("expect slog level %v mapped to zap %s, but get %s", v.level, v.expect, zl)
+		}
+	}
+}
+
+func TestSlogHandlerContext(t *testing.T) {
+	var entries []recordEntry
+	l := slog.New(NewSlogHandler(recordLogger{entries: &entries}))
+
+	ctx := requestid.NewContext(context.Background(), "rid")
+	ctx = NewContext(ctx, "user", 1)
+	l.With("a", 1).InfoContext(ctx, "msg", "k", "v")
+
+	expect := []interface{}{"a", int64(1), "request_id", "rid", "user", 1, "k", "v"}
+	if len(entries) != 1 || !reflect.DeepEqual(entries[0].kvs, expect) {
+		t.Fatalf("expect %v, but get %v", expect, entries)
+	}
+}
+
+func TestSlogHandlerZap(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	options := []zap.Option{zap.AddCaller(), zap.AddCallerSkip(1)}
+	z := &ZapLogger{
+		stdout: zap.New(core, options...).Sugar(),
+		stderr: zap.New(core, options...).Sugar(),
+	}
+	l := z.Slog()
+
+	ctx := requestid.NewContext(context.Background(), "rid")
+	if l.Enabled(ctx, slog.LevelDebug) {
+		t.Error("expect debug disabled by zap level")
+	}
+	l.DebugContext(ctx, "debug")
+	l.WithGroup("g").WarnContext(ctx, "warn", "k", "v")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("expect 1 entry, but get %d", len(entries))
+	}
+	e := entries[0]
+	if e.Level != zapcore.WarnLevel || e.Message != "warn" {
+		t.Errorf("unexpect entry %v", e.Entry)
+	}
+	if fields := e.ContextMap(); fields["request_id"] != "rid" || fields["g.k"] != "v" {
+		t.Errorf("unexpect fields %v", fields)
+	}
+	// 调用位置为 slog 的调用方
+	if !strings.HasSuffix(e.Caller.File, "xlogger/slog_test.go") {
+		t.Errorf("expect caller in slog_test.go, but get %s", e.Caller.File)
+	}
+}
+
+func TestFromSlog(t *testing.T) {
+	var buf bytes.Buffer
+	l := FromSlog(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	ctx := requestid.NewContext(context.Background(), "rid")
+	WithContext(ctx, l).Warn("msg", "k", "v")
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["level"] != "WARN" || got["msg"] != "msg" || got["k"] != "v" || got["request_id"] != "rid" {
+		t.Errorf("unexpect record %v", got)
+	}
+}
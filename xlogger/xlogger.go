@@ -1,5 +1,7 @@
 package xlogger
 
+import "context"
+
 type Logger interface {
 	Debug(msg string, kvs ...interface{})
 	Info(msg string, kvs ...interface{})
@@ -7,3 +9,13 @@ type Logger interface {
 	Error(msg string, kvs ...interface{})
 	Fatal(msg string, kvs ...interface{})
 }
+
+// ContextLogger 可以派生附带字段的 Logger, ZapLogger 等内置 Logger 均已实现;
+// 自定义 Logger 可以不实现, WithContext 会包装为每次输出时附带字段的 Logger
+type ContextLogger interface {
+	Logger
+	// With 返回一个附带 kvs 字段的 Logger
+	With(kvs ...interface{}) Logger
+	// WithContext 返回一个附带 ctx 中请求 ID 以及 NewContext 保存的字段的 Logger
+	WithContext(ctx context.Context) Logger
+}
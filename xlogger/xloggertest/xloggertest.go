@@ -0,0 +1,230 @@
+// Package xloggertest 提供记录日志内容的 xlogger.Logger 实现, 用于测试
+package xloggertest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"skuld/xlogger"
+)
+
+type Level string
+
+const (
+	DebugLevel Level = "debug"
+	InfoLevel  Level = "info"
+	WarnLevel  Level = "warn"
+	ErrorLevel Level = "error"
+	FatalLevel Level = "fatal"
+)
+
+// Entry 一条日志
+type Entry struct {
+	Level Level
+	Msg   string
+	// KVs 调用日志方法时传入的 key / value
+	KVs []interface{}
+	// Fields 通过 With 附带的 key / value
+	Fields []interface{}
+	// Context 通过 WithContext 从 ctx 中读取的 key / value
+	Context []interface{}
+}
+
+// Value 依次在 KVs, Fields, Context 中查找 key 对应的值
+func (e Entry) Value(key string) (interface{}, bool) {
+	for _, kvs := range [][]interface{}{e.KVs, e.Fields, e.Context} {
+		for i := 0; i+1 < len(kvs); i += 2 {
+			if k, ok := kvs[i].(string); ok && k == key {
+				return kvs[i+1], true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (e Entry) String() string {
+	return fmt.Sprintf("[%s] %s %v %v %v", e.Level, e.Msg, e.KVs, e.Fields, e.Context)
+}
+
+type recorder struct {
+	mu       sync.Mutex
+	entries  []Entry
+	expected map[string]struct{}
+}
+
+// Logger 记录所有日志的 xlogger.Logger, Fatal 只记录不退出进程
+type Logger struct {
+	rec    *recorder
+	fields []interface{}
+	ctx    []interface{}
+}
+
+var _ xlogger.Logger = (*Logger)(nil)
+
+func New() *Logger {
+	return &Logger{rec: &recorder{expected: make(map[string]struct{})}}
+}
+
+// NewT 创建 Logger, 测试结束时如果记录了未通过 ExpectError 声明的 Error / Fatal 日志, 测试失败
+func NewT(t testing.TB) *Logger {
+	l := New()
+	t.Cleanup(func() {
+		for _, e := range l.Entries() {
+			if e.Level != ErrorLevel && e.Level != FatalLevel {
+				continue
+			}
+			l.rec.mu.Lock()
+			_, ok := l.rec.expected[e.Msg]
+			l.rec.mu.Unlock()
+			if !ok {
+				t.Errorf("unexpected %s log: %s", e.Level, e)
+			}
+		}
+	})
+	return l
+}
+
+// ExpectError 声明 msg 对应的 Error / Fatal 日志是预期内的
+func (l *Logger) ExpectError(msg string) {
+	l.rec.mu.Lock()
+	defer l.rec.mu.Unlock()
+	l.rec.expected[msg] = struct{}{}
+}
+
+func (l *Logger) log(level Level, msg string, kvs []interface{}) {
+	// 调用方可能复用 kvs 的底层数组, 记录副本
+	cp := make([]interface{}, len(kvs))
+	copy(cp, kvs)
+
+	l.rec.mu.Lock()
+	defer l.rec.mu.Unlock()
+	l.rec.entries = append(l.rec.entries, Entry{
+		Level:   level,
+		Msg:     msg,
+		KVs:     cp,
+		Fields:  l.fields,
+		Context: l.ctx,
+	})
+}
+
+func (l *Logger) Debug(msg string, kvs ...interface{}) {
+	l.log(DebugLevel, msg, kvs)
+}
+
+func (l *Logger) Info(msg string, kvs ...interface{}) {
+	l.log(InfoLevel, msg, kvs)
+}
+
+func (l *Logger) Warn(msg string, kvs ...interface{}) {
+	l.log(WarnLevel, msg, kvs)
+}
+
+func (l *Logger) Error(msg string, kvs ...interface{}) {
+	l.log(ErrorLevel, msg, kvs)
+}
+
+func (l *Logger) Fatal(msg string, kvs ...interface{}) {
+	l.log(FatalLevel, msg, kvs)
+}
+
+func (l *Logger) With(kvs ...interface{}) xlogger.Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(kvs))
+	fields = append(fields, l.fields...)
+	fields = append(fields, kvs...)
+	return &Logger{rec: l.rec, fields: fields, ctx: l.ctx}
+}
+
+func (l *Logger) WithContext(ctx context.Context) xlogger.Logger {
+	kvs := xlogger.FromContext(ctx)
+	fields := make([]interface{}, 0, len(l.ctx)+len(kvs))
+	fields = append(fields, l.ctx...)
+	fields = append(fields, kvs...)
+	return &Logger{rec: l.rec, fields: l.fields, ctx: fields}
+}
+
+// Entries 返回所有记录的日志, 包括通过 With / WithContext 派生的 Logger 记录的日志
+func (l *Logger) Entries() []Entry {
+	l.rec.mu.Lock()
+	defer l.rec.mu.Unlock()
+	entries := make([]Entry, len(l.rec.entries))
+	copy(entries, l.rec.entries)
+	return entries
+}
+
+// FilterLevel 返回指定级别的日志
+func (l *Logger) FilterLevel(level Level) []Entry {
+	return l.Filter(func(e Entry) bool { return e.Level == level })
+}
+
+// FilterMessage 返回指定内容的日志
+func (l *Logger) FilterMessage(msg string) []Entry {
+	return l.Filter(func(e Entry) bool { return e.Msg == msg })
+}
+
+// Filter 返回 f 返回 true 的日志
+func (l *Logger) Filter(f func(Entry) bool) []Entry {
+	rst := make([]Entry, 0)
+	for _, e := range l.Entries() {
+		if f(e) {
+			rst = append(rst, e)
+		}
+	}
+	return rst
+}
+
+// Reset 清空记录的日志
+func (l *Logger) Reset() {
+	l.rec.mu.Lock()
+	defer l.rec.mu.Unlock()
+	l.rec.entries = nil
+}
+
+// Logged 判断是否记录过级别为 level, 内容为 msg 且包含 kvs 的日志
+func (l *Logger) Logged(level Level, msg string, kvs ...interface{}) bool {
+	for _, e := range l.Entries() {
+		if e.Level == level && e.Msg == msg && contains(e, kvs) {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertLogged 未记录过级别为 level, 内容为 msg 且包含 kvs 的日志时测试失败
+func (l *Logger) AssertLogged(t testing.TB, level Level, msg string, kvs ...interface{}) {
+	t.Helper()
+	if !l.Logged(level, msg, kvs...) {
+		t.Errorf("expect [%s] %s %v logged, but get %v", level, msg, kvs, l.Entries())
+	}
+}
+
+// AssertNotLogged 记录过级别为 level, 内容为 msg 的日志时测试失败
+func (l *Logger) AssertNotLogged(t testing.TB, level Level, msg string) {
+	t.Helper()
+	if l.Logged(level, msg) {
+		t.Errorf("expect [%s] %s not logged", level, msg)
+	}
+}
+
+// AssertNoErrors 记录过 Error / Fatal 日志时测试失败
+func (l *Logger) AssertNoErrors(t testing.TB) {
+	t.Helper()
+	for _, e := range l.Entries() {
+		if e.Level == ErrorLevel || e.Level == FatalLevel {
+			t.Errorf("unexpected %s log: %s", e.Level, e)
+		}
+	}
+}
+
+func contains(e Entry, kvs []interface{}) bool {
+	for i := 0; i+1 < len(kvs); i += 2 {
+		key, _ := kvs[i].(string)
+		v, ok := e.Value(key)
+		if !ok || !reflect.DeepEqual(v, kvs[i+1]) {
+			return false
+		}
+	}
+	return true
+}
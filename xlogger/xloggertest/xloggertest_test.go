@@ -0,0 +1,113 @@
+package xloggertest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"skuld/requestid"
+	"skuld/xlogger"
+)
+
+func TestLoggerCopyKVs(t *testing.T) {
+	l := New()
+	kvs := []interface{}{"k", "v"}
+	l.Info("msg", kvs...)
+	kvs[1] = "changed"
+
+	if !l.Logged(InfoLevel, "msg", "k", "v") {
+		t.Fatalf("expect recorded kvs not changed by caller, but get %v", l.Entries())
+	}
+}
+
+func TestLoggerWith(t *testing.T) {
+	l := New()
+	ctx := requestid.NewContext(context.Background(), "rid")
+	child := xlogger.WithContext(ctx, l.With("a", 1))
+	child.Warn("msg", "k", "v")
+	l.Debug("root")
+
+	entries := l.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expect 2 entries recorded by root logger, but get %v", entries)
+	}
+	e := entries[0]
+	if !reflect.DeepEqual(e.KVs, []interface{}{"k", "v"}) ||
+		!reflect.DeepEqual(e.Fields, []interface{}{"a", 1}) ||
+		!reflect.DeepEqual(e.Context, []interface{}{"request_id", "rid"}) {
+		t.Errorf("unexpect entry %s", e)
+	}
+	for _, key := range []string{"k", "a", "request_id"} {
+		if _, ok := e.Value(key); !ok {
+			t.Errorf("expect value of %s", key)
+		}
+	}
+	if len(entries[1].Fields) != 0 {
+		t.Errorf("expect root logger without fields, but get %v", entries[1].Fields)
+	}
+}
+
+func TestLoggerFilter(t *testing.T) {
+	l := New()
+	l.Info("a", "n", 1)
+	l.Warn("a", "n", 2)
+	l.Info("b")
+
+	if n := len(l.FilterLevel(InfoLevel)); n != 2 {
+		t.Errorf("expect 2 info entries, but get %d", n)
+	}
+	if n := len(l.FilterMessage("a")); n != 2 {
+		t.Errorf("expect 2 entries of a, but get %d", n)
+	}
+	if !l.Logged(WarnLevel, "a", "n", 2) || l.Logged(WarnLevel, "a", "n", 1) || l.Logged(InfoLevel, "a", "m", 1) {
+		t.Error("unexpect Logged result")
+	}
+
+	l.AssertLogged(t, InfoLevel, "b")
+	l.AssertNotLogged(t, ErrorLevel, "a")
+	l.AssertNoErrors(t)
+
+	l.Reset()
+	if n := len(l.Entries()); n != 0 {
+		t.Errorf("expect no entries after reset, but get %d", n)
+	}
+}
+
+func TestNewT(t *testing.T) {
+	// 未声明的 Error 日志在 Cleanup 时使测试失败
+	mock := &cleanupT{TB: t}
+	l := NewT(mock)
+	l.Warn("warn")
+	l.Error("boom")
+	mock.cleanup()
+	if !mock.failed {
+		t.Error("expect unexpected error log fails test")
+	}
+
+	mock = &cleanupT{TB: t}
+	l = NewT(mock)
+	l.ExpectError("boom")
+	l.Error("boom")
+	l.Fatal("boom")
+	mock.cleanup()
+	if mock.failed {
+		t.Error("expect error declared by ExpectError not fail test")
+	}
+}
+
+// cleanupT 记录 Cleanup 函数和 Errorf 调用, 用于测试 NewT
+type cleanupT struct {
+	testing.TB
+	fns    []func()
+	failed bool
+}
+
+func (c *cleanupT) Cleanup(f func()) { c.fns = append(c.fns, f) }
+
+func (c *cleanupT) Errorf(format string, args ...interface{}) { c.failed = true }
+
+func (c *cleanupT) cleanup() {
+	for i := len(c.fns) - 1; i >= 0; i-- {
+		c.fns[i]()
+	}
+}
@@ -1,71 +1,460 @@
 package xlogger
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"runtime"
+	"sync"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"skuld/xlogger/redact"
+	"skuld/xlogger/report"
 )
 
 type ZapLogger struct {
-	stdout *zap.SugaredLogger
-	stderr *zap.SugaredLogger
+	stdout   *zap.SugaredLogger
+	stderr   *zap.SugaredLogger
+	levels   *Levels
+	module   string
+	files    *fileSinks
+	redactor *redact.Redactor
+	sampler  *sampler
+	// stopReopen 停止监听 WithReopenSignal 设置的信号
+	stopReopen func()
+}
+
+func (z *ZapLogger) clone() *ZapLogger {
+	cp := *z
+	return &cp
+}
+
+func (z *ZapLogger) redact(kvs []interface{}) []interface{} {
+	if z.redactor == nil {
+		return kvs
+	}
+	return z.redactor.KVs(kvs)
 }
 
 func (z *ZapLogger) Debug(msg string, kvs ...interface{}) {
-	z.stdout.Debugw(msg, kvs...)
+	z.write(zapcore.DebugLevel, msg, 0, kvs)
 }
 
 func (z *ZapLogger) Info(msg string, kvs ...interface{}) {
-	z.stdout.Infow(msg, kvs...)
+	z.write(zapcore.InfoLevel, msg, 0, kvs)
 }
 
 func (z *ZapLogger) Warn(msg string, kvs ...interface{}) {
-	z.stdout.Warnw(msg, kvs...)
+	z.write(zapcore.WarnLevel, msg, 0, kvs)
 }
 
 func (z *ZapLogger) Error(msg string, kvs ...interface{}) {
-	z.stderr.Errorw(msg, kvs...)
+	z.write(zapcore.ErrorLevel, msg, 0, kvs)
 }
 
 func (z *ZapLogger) Fatal(msg string, kvs ...interface{}) {
-	z.stderr.Fatalw(msg, kvs...)
+	z.write(zapcore.FatalLevel, msg, 0, kvs)
 }
 
-func NewZapLogger(kv ...string) (Logger, error) {
+// write 先经过级别过滤和采样, 确定输出后再脱敏 kvs, 避免为被丢弃的日志脱敏;
+// pc 不为 0 时以 pc 作为调用位置, 否则为调用 Debug / Info 等方法的位置
+func (z *ZapLogger) write(l zapcore.Level, msg string, pc uintptr, kvs []interface{}) {
+	log := z.stdout.Desugar()
+	if l >= zapcore.ErrorLevel {
+		log = z.stderr.Desugar()
+	}
+	if pc == 0 {
+		// 跳过 write 本身
+		log = log.WithOptions(zap.AddCallerSkip(1))
+	}
+	ce := log.Check(l, msg)
+	if ce == nil {
+		return
+	}
+	if pc != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		ce.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+
+	kvs = z.redact(kvs)
+	fields := make([]zap.Field, 0, len(kvs)/2)
+	for i := 0; i < len(kvs); i++ {
+		if f, ok := kvs[i].(zap.Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+		if i == len(kvs)-1 {
+			fields = append(fields, zap.Any("ignored", kvs[i]))
+			break
+		}
+		key, ok := kvs[i].(string)
+		if !ok {
+			key = fmt.Sprint(kvs[i])
+		}
+		fields = append(fields, zap.Any(key, kvs[i+1]))
+		i++
+	}
+	ce.Write(fields...)
+}
+
+func (z *ZapLogger) With(kvs ...interface{}) Logger {
+	if len(kvs) == 0 {
+		return z
+	}
+	kvs = z.redact(kvs)
+	l := z.clone()
+	l.stdout = z.stdout.With(kvs...)
+	l.stderr = z.stderr.With(kvs...)
+	return l
+}
+
+func (z *ZapLogger) WithContext(ctx context.Context) Logger {
+	return z.With(FromContext(ctx)...)
+}
+
+// Named 返回一个名称为 name 的子 Logger, 可以通过 Levels().SetModuleLevel 单独调整其日志级别
+//
+// 多次调用时名称以 "." 连接, 如 Named("db").Named("xorm") 的模块名称为 db.xorm
+func (z *ZapLogger) Named(name string) *ZapLogger {
+	module := name
+	if z.module != "" {
+		module = z.module + "." + name
+	}
+
+	enabler := z.levels.enabler(module)
+	wrap := zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if lc, ok := core.(levelCore); ok {
+			core = lc.Core
+		}
+		return levelCore{Core: core, enabler: enabler}
+	})
+
+	l := z.clone()
+	l.stdout = z.stdout.Desugar().Named(name).WithOptions(wrap).Sugar()
+	l.stderr = z.stderr.Desugar().Named(name).WithOptions(wrap).Sugar()
+	l.module = module
+	return l
+}
+
+// Levels 返回可在运行时修改的日志级别, 其本身实现了 http.Handler 可以挂载到管理端口
+func (z *ZapLogger) Levels() *Levels {
+	return z.levels
+}
+
+// SetLevel 修改全局日志级别
+func (z *ZapLogger) SetLevel(level string) error {
+	return z.levels.SetLevel(level)
+}
+
+// Sync 将缓冲区中的日志写入输出
+func (z *ZapLogger) Sync() error {
+	errOut := z.stdout.Sync()
+	errErr := z.stderr.Sync()
+	if errOut != nil {
+		return errOut
+	}
+	return errErr
+}
+
+// Reopen 关闭所有日志文件, 下一次写入时重新打开, 用于配合 logrotate 等外部工具切割日志
+func (z *ZapLogger) Reopen() error {
+	return z.files.close()
+}
+
+// Close 停止监听 WithReopenSignal 设置的信号, 输出采样汇总, 刷新缓冲区并关闭所有日志文件
+func (z *ZapLogger) Close() error {
+	if z.stopReopen != nil {
+		z.stopReopen()
+	}
+	if z.sampler != nil {
+		z.sampler.close()
+	}
+	_ = z.Sync()
+	return z.files.close()
+}
+
+// Rotation 日志文件切割配置
+type Rotation struct {
+	// MaxSize 单个日志文件的最大大小, 单位 MB, 默认 100
+	MaxSize int `mapstructure:"max_size"`
+	// MaxAge 切割后的日志文件保留天数, 0 表示不按时间删除
+	MaxAge int `mapstructure:"max_age"`
+	// MaxBackups 切割后的日志文件保留个数, 0 表示不按个数删除
+	MaxBackups int `mapstructure:"max_backups"`
+	// Compress 是否使用 gzip 压缩切割后的日志文件
+	Compress bool `mapstructure:"compress"`
+	// LocalTime 切割后的文件名是否使用本地时间, 默认 UTC
+	LocalTime bool `mapstructure:"local_time"`
+}
+
+type ZapOption func(*zapOptions)
+
+type zapOptions struct {
+	encoding     string
+	level        string
+	outputs      []string
+	errorOutputs []string
+	rotation     Rotation
+	development  bool
+	fields       []string
+	reopen       []os.Signal
+	redactor     *redact.Redactor
+	sampling     *Sampling
+	reporter     report.Reporter
+}
+
+// WithEncoding 设置日志格式, 支持 json 和 console, 默认 json
+func WithEncoding(encoding string) ZapOption {
+	return func(o *zapOptions) {
+		o.encoding = encoding
+	}
+}
+
+// WithLevel 设置初始日志级别, 默认 info
+func WithLevel(level string) ZapOption {
+	return func(o *zapOptions) {
+		o.level = level
+	}
+}
+
+// WithOutputs 设置 Debug / Info / Warn 日志的输出, stdout 和 stderr 表示标准输出, 其他值表示文件路径, 默认 stdout
+func WithOutputs(outputs ...string) ZapOption {
+	return func(o *zapOptions) {
+		o.outputs = outputs
+	}
+}
+
+// WithErrorOutputs 设置 Error / Fatal 日志的输出, 取值同 WithOutputs, 默认 stderr
+func WithErrorOutputs(outputs ...string) ZapOption {
+	return func(o *zapOptions) {
+		o.errorOutputs = outputs
+	}
+}
+
+// WithRotation 设置日志文件切割, 仅对文件输出生效
+func WithRotation(r Rotation) ZapOption {
+	return func(o *zapOptions) {
+		o.rotation = r
+	}
+}
+
+// WithDevelopment 开发模式下 DPanic 级别的日志会触发 panic
+func WithDevelopment(development bool) ZapOption {
+	return func(o *zapOptions) {
+		o.development = development
+	}
+}
+
+// WithFields 设置每条日志都会附带的字段, kv 为 key1, value1, key2, value2 ...
+func WithFields(kv ...string) ZapOption {
+	return func(o *zapOptions) {
+		o.fields = append(o.fields, kv...)
+	}
+}
+
+// WithReopenSignal 收到 sigs 时重新打开日志文件, 如 syscall.SIGHUP, Close 后停止监听
+func WithReopenSignal(sigs ...os.Signal) ZapOption {
+	return func(o *zapOptions) {
+		o.reopen = sigs
+	}
+}
+
+// WithRedactor 输出日志前对 key / value 脱敏, 如 WithRedactor(redact.Default())
+func WithRedactor(r *redact.Redactor) ZapOption {
+	return func(o *zapOptions) {
+		o.redactor = r
+	}
+}
+
+// WithSampling 按级别对相同内容的日志采样, 并周期性输出被丢弃日志的数量
+func WithSampling(s Sampling) ZapOption {
+	return func(o *zapOptions) {
+		o.sampling = &s
+	}
+}
+
+// WithReporter 将 Error / Fatal 日志连同调用栈转发给 r, 如
+// WithReporter(report.Limit(report.NewWebhook(url))) 去重限流后发送到 webhook
+func WithReporter(r report.Reporter) ZapOption {
+	return func(o *zapOptions) {
+		o.reporter = r
+	}
+}
+
+// NewZap 根据 opts 创建 ZapLogger, 默认以 json 格式将 Debug / Info / Warn 输出到 stdout, Error / Fatal 输出到 stderr
+func NewZap(opts ...ZapOption) (*ZapLogger, error) {
+	o := zapOptions{
+		encoding:     "json",
+		level:        "info",
+		outputs:      []string{"stdout"},
+		errorOutputs: []string{"stderr"},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if len(o.fields)%2 == 1 {
+		return nil, errors.New("there is no one-to-one correspondence between key and value")
+	}
+	level, err := parseLevel(o.level)
+	if err != nil {
+		return nil, err
+	}
+	levels := newLevels(level)
+
 	encoderConfig := zap.NewProductionEncoderConfig()
 	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	stdoutCore := zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderConfig),
-		zapcore.NewMultiWriteSyncer(zapcore.AddSync(os.Stdout)),
-		zap.InfoLevel,
-	)
-	stderrCore := zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderConfig),
-		zapcore.NewMultiWriteSyncer(zapcore.AddSync(os.Stderr)),
-		zap.InfoLevel,
-	)
+	var encoder zapcore.Encoder
+	switch o.encoding {
+	case "json":
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case "console":
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return nil, fmt.Errorf("unknown log encoding: %s", o.encoding)
+	}
+
+	files := &fileSinks{rotation: o.rotation, files: make(map[string]*lumberjack.Logger)}
+	// 级别过滤由 levelCore 完成, 底层 core 接收所有级别
+	stdoutCore := zapcore.NewCore(encoder, files.syncer(o.outputs), zap.DebugLevel)
+	stderrCore := zapcore.NewCore(encoder.Clone(), files.syncer(o.errorOutputs), zap.DebugLevel)
+
+	var sp *sampler
+	if o.sampling != nil && len(o.sampling.Rules) > 0 {
+		if sp, err = newSampler(*o.sampling, stdoutCore, stderrCore); err != nil {
+			return nil, err
+		}
+		stdoutCore = sampleCore{Core: stdoutCore, sampler: sp}
+		stderrCore = sampleCore{Core: stderrCore, sampler: sp}
+	}
+	// 在采样之前上报, 被采样丢弃的错误也会交给 Reporter 去重计数
+	if o.reporter != nil {
+		stderrCore = reportCore{Core: stderrCore, reporter: o.reporter}
+	}
 
 	options := []zap.Option{
 		zap.AddCaller(),
 		zap.AddCallerSkip(1),
-		zap.Development(),
 		zap.AddStacktrace(zap.ErrorLevel),
 	}
-	if len(kv)%2 == 1 {
-		return nil, errors.New("there is no one-to-one correspondence between key and value")
+	if o.development {
+		options = append(options, zap.Development())
 	}
 	fields := make([]zap.Field, 0)
-	for i := 0; i < len(kv); i += 2 {
-		fields = append(fields, zap.String(kv[i], kv[i+1]))
+	for i := 0; i < len(o.fields); i += 2 {
+		fields = append(fields, zap.String(o.fields[i], o.fields[i+1]))
 	}
 	if len(fields) > 0 {
 		options = append(options, zap.Fields(fields...))
 	}
 
-	return &ZapLogger{
-		stdout: zap.New(stdoutCore, options...).Sugar(),
-		stderr: zap.New(stderrCore, options...).Sugar(),
-	}, nil
+	z := &ZapLogger{
+		stdout:   zap.New(levelCore{Core: stdoutCore, enabler: levels.enabler("")}, options...).Sugar(),
+		stderr:   zap.New(levelCore{Core: stderrCore, enabler: levels.enabler("")}, options...).Sugar(),
+		levels:   levels,
+		files:    files,
+		redactor: o.redactor,
+		sampler:  sp,
+	}
+
+	if len(o.reopen) > 0 && len(files.files) > 0 {
+		z.stopReopen = z.watchReopen(o.reopen)
+	}
+
+	return z, nil
+}
+
+// watchReopen 收到 sigs 时调用 Reopen, 返回的函数用于停止监听
+func (z *ZapLogger) watchReopen(sigs []os.Signal) func() {
+	ch := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ch:
+				if err := z.Reopen(); err != nil {
+					z.Error("ZapLogger reopen", "err", err)
+				}
+			}
+		}
+	}()
+	return sync.OnceFunc(func() {
+		signal.Stop(ch)
+		close(stop)
+	})
+}
+
+// NewZapLogger 创建以 json 格式输出到 stdout / stderr 的开发模式 ZapLogger, kv 为每条日志都会附带的字段;
+// 运行时修改日志级别通过 LevelsOf 获取
+func NewZapLogger(kv ...string) (Logger, error) {
+	z, err := NewZap(WithDevelopment(true), WithFields(kv...))
+	if err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// fileSinks 管理日志文件输出, 相同路径共用一个文件
+type fileSinks struct {
+	rotation Rotation
+	mu       sync.Mutex
+	files    map[string]*lumberjack.Logger
+}
+
+func (f *fileSinks) syncer(outputs []string) zapcore.WriteSyncer {
+	syncers := make([]zapcore.WriteSyncer, 0, len(outputs))
+	for _, output := range outputs {
+		switch output {
+		case "stdout":
+			syncers = append(syncers, zapcore.AddSync(os.Stdout))
+		case "stderr":
+			syncers = append(syncers, zapcore.AddSync(os.Stderr))
+		default:
+			syncers = append(syncers, zapcore.AddSync(f.file(output)))
+		}
+	}
+	return zapcore.NewMultiWriteSyncer(syncers...)
+}
+
+func (f *fileSinks) file(path string) *lumberjack.Logger {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if l, ok := f.files[path]; ok {
+		return l
+	}
+	l := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    f.rotation.MaxSize,
+		MaxAge:     f.rotation.MaxAge,
+		MaxBackups: f.rotation.MaxBackups,
+		Compress:   f.rotation.Compress,
+		LocalTime:  f.rotation.LocalTime,
+	}
+	f.files[path] = l
+	return l
+}
+
+func (f *fileSinks) close() error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var err error
+	for _, l := range f.files {
+		if e := l.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
 }
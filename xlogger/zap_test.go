@@ -0,0 +1,46 @@
+package xlogger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestZapReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	z, err := NewZap(WithOutputs(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+
+	z.Info("before")
+	// 模拟 logrotate 移走日志文件
+	rotated := filepath.Join(dir, "app.log.1")
+	if err = os.Rename(path, rotated); err != nil {
+		t.Fatal(err)
+	}
+	z.Info("rotated")
+	if err = z.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	z.Info("after")
+
+	old, err := os.ReadFile(rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reopen 之前仍写入已打开的文件
+	if !strings.Contains(string(old), "before") || !strings.Contains(string(old), "rotated") {
+		t.Errorf("expect logs before reopen in rotated file, but get %s", old)
+	}
+	cur, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(cur), "after") || strings.Contains(string(cur), "before") {
+		t.Errorf("expect only logs after reopen in new file, but get %s", cur)
+	}
+}
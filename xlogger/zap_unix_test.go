@@ -0,0 +1,51 @@
+//go:build unix
+
+package xlogger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestZapReopenSignal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	z, err := NewZap(WithOutputs(path), WithReopenSignal(syscall.SIGUSR1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if z.stopReopen == nil {
+		t.Fatal("expect reopen signal watched")
+	}
+
+	z.Info("before")
+	if err = os.Rename(path, filepath.Join(dir, "app.log.1")); err != nil {
+		t.Fatal(err)
+	}
+	if err = syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+
+	// 信号异步处理, 重新打开之前的日志仍写入旧文件
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		z.Info("after")
+		if data, _ := os.ReadFile(path); strings.Contains(string(data), "after") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expect log file reopened after signal")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Close 停止监听, 重复调用不会 panic
+	if err = z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	z.stopReopen()
+}
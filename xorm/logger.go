@@ -0,0 +1,114 @@
+package xorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"skuld/xlogger"
+)
+
+// Logger 将 gorm 的日志写入 xlogger.Logger, 日志附带 sql, 影响行数, 耗时, 业务调用位置以及 ctx 中的请求 ID
+type Logger struct {
+	logger         xlogger.Logger
+	level          logger.LogLevel
+	slowThreshold  time.Duration
+	ignoreNotFound bool
+}
+
+// NewLogger level 为 silent / error / warn / info, 为空时使用 warn;
+// slowThreshold 小于等于 0 时不记录慢查询
+func NewLogger(l xlogger.Logger, level string, slowThreshold time.Duration) *Logger {
+	return &Logger{
+		logger:         l,
+		level:          ParseLogLevel(level),
+		slowThreshold:  slowThreshold,
+		ignoreNotFound: true,
+	}
+}
+
+// ParseLogLevel 将 silent / error / warn / info 转换为 gorm 的日志级别, 无法识别时返回 warn
+func ParseLogLevel(level string) logger.LogLevel {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "info":
+		return logger.Info
+	default:
+		return logger.Warn
+	}
+}
+
+func (l *Logger) LogMode(level logger.LogLevel) logger.Interface {
+	cp := *l
+	cp.level = level
+	return &cp
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Info {
+		xlogger.WithContext(ctx, l.logger).Info(fmt.Sprintf(msg, data...), "caller", caller())
+	}
+}
+
+func (l *Logger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Warn {
+		xlogger.WithContext(ctx, l.logger).Warn(fmt.Sprintf(msg, data...), "caller", caller())
+	}
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Error {
+		xlogger.WithContext(ctx, l.logger).Error(fmt.Sprintf(msg, data...), "caller", caller())
+	}
+}
+
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	isErr := err != nil && !(l.ignoreNotFound && errors.Is(err, gorm.ErrRecordNotFound))
+	isSlow := l.slowThreshold > 0 && elapsed > l.slowThreshold
+	switch {
+	case isErr && l.level >= logger.Error:
+		sql, rows := fc()
+		xlogger.WithContext(ctx, l.logger).Error("gorm query error",
+			"sql", sql, "rows", rows, "duration", elapsed.Milliseconds(), "caller", caller(), "err", err)
+	case isSlow && l.level >= logger.Warn:
+		sql, rows := fc()
+		xlogger.WithContext(ctx, l.logger).Warn("gorm slow query",
+			"sql", sql, "rows", rows, "duration", elapsed.Milliseconds(), "caller", caller(),
+			"slow_threshold", l.slowThreshold.Milliseconds())
+	case l.level >= logger.Info:
+		sql, rows := fc()
+		xlogger.WithContext(ctx, l.logger).Info("gorm query",
+			"sql", sql, "rows", rows, "duration", elapsed.Milliseconds(), "caller", caller())
+	}
+}
+
+// caller 返回 gorm 和 xorm 之外的第一个调用位置
+func caller() string {
+	pcs := make([]uintptr, 20)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "gorm.io/") && !strings.HasPrefix(frame.Function, "skuld/xorm.") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package xorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"skuld/requestid"
+	"skuld/xlogger/xloggertest"
+)
+
+func TestLoggerTrace(t *testing.T) {
+	ctx := requestid.NewContext(context.Background(), "rid")
+	fc := func() (string, int64) { return "SELECT 1", 1 }
+	fast := time.Now()
+	slow := time.Now().Add(-time.Second)
+	boom := errors.New("boom")
+
+	type testCase struct {
+		name   string
+		level  string
+		begin  time.Time
+		err    error
+		expect xloggertest.Level
+		msg    string
+	}
+	testTable := []testCase{
+		{name: "error", level: "error", begin: fast, err: boom, expect: xloggertest.ErrorLevel, msg: "gorm query error"},
+		{name: "error silent", level: "silent", begin: fast, err: boom},
+		{name: "not found ignored", level: "warn", begin: fast, err: gorm.ErrRecordNotFound},
+		{name: "not found as query", level: "info", begin: fast, err: gorm.ErrRecordNotFound, expect: xloggertest.InfoLevel, msg: "gorm query"},
+		{name: "slow", level: "warn", begin: slow, expect: xloggertest.WarnLevel, msg: "gorm slow query"},
+		{name: "slow filtered", level: "error", begin: slow},
+		{name: "query", level: "info", begin: fast, expect: xloggertest.InfoLevel, msg: "gorm query"},
+		{name: "query filtered", level: "warn", begin: fast},
+	}
+
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			l := xloggertest.NewT(t)
+			l.ExpectError("gorm query error")
+			NewLogger(l, v.level, 100*time.Millisecond).Trace(ctx, v.begin, fc, v.err)
+
+			entries := l.Entries()
+			if v.msg == "" {
+				if len(entries) != 0 {
+					t.Fatalf("expect nothing logged, but get %v", entries)
+				}
+				return
+			}
+			l.AssertLogged(t, v.expect, v.msg, "sql", "SELECT 1", "rows", int64(1), "request_id", "rid")
+			// 测试代码本身属于 skuld/xorm, 调用位置被跳过到 testing 包, 这里只检查字段存在
+			if c, _ := entries[0].Value("caller"); c == nil || c == "" {
+				t.Error("expect caller logged")
+			}
+		})
+	}
+}
+
+func TestLoggerSlowDisabled(t *testing.T) {
+	l := xloggertest.NewT(t)
+	NewLogger(l, "warn", 0).Trace(context.Background(), time.Now().Add(-time.Hour), func() (string, int64) { return "SELECT 1", 1 }, nil)
+	l.AssertNotLogged(t, xloggertest.WarnLevel, "gorm slow query")
+}
+
+func TestLoggerLogMode(t *testing.T) {
+	l := xloggertest.NewT(t)
+	base := NewLogger(l, "warn", 0)
+	base.Info(context.Background(), "info %d", 1)
+	base.LogMode(logger.Info).Info(context.Background(), "info %d", 2)
+	base.Warn(context.Background(), "warn %s", "a")
+	base.LogMode(logger.Silent).Warn(context.Background(), "warn %s", "b")
+
+	l.AssertNotLogged(t, xloggertest.InfoLevel, "info 1")
+	l.AssertLogged(t, xloggertest.InfoLevel, "info 2")
+	l.AssertLogged(t, xloggertest.WarnLevel, "warn a")
+	l.AssertNotLogged(t, xloggertest.WarnLevel, "warn b")
+}
+
+func TestParseLogLevel(t *testing.T) {
+	expect := map[string]logger.LogLevel{
+		"silent": logger.Silent, "ERROR": logger.Error, "warn": logger.Warn, "info": logger.Info, "": logger.Warn, "bad": logger.Warn,
+	}
+	for level, l := range expect {
+		if got := ParseLogLevel(level); got != l {
+			t.Errorf("expect %q parsed to %v, but get %v", level, l, got)
+		}
+	}
+}
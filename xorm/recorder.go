@@ -0,0 +1,84 @@
+package xorm
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Recorder 记录每条 sql 的操作类型 (create / query / update / delete / row / raw), 耗时和结果,
+// 可以实现该接口将数据导出为监控指标, gorm.ErrRecordNotFound 不视为错误
+type Recorder interface {
+	Record(op string, d time.Duration, err error)
+}
+
+const recorderStartKey = "skuld:recorder:start"
+
+type recorderPlugin struct {
+	recorder Recorder
+}
+
+// NewRecorderPlugin 返回将 sql 执行结果交给 r 的 gorm 插件, 通过 gorm.DB.Use 注册
+func NewRecorderPlugin(r Recorder) gorm.Plugin {
+	return &recorderPlugin{recorder: r}
+}
+
+func (p *recorderPlugin) Name() string {
+	return "skuld:recorder"
+}
+
+func (p *recorderPlugin) Initialize(db *gorm.DB) error {
+	return registerCallbacks(db, "skuld:recorder", p.before, p.after)
+}
+
+// registerCallbacks 在 gorm 的 create / query / update / delete / row / raw 回调前后注册 before / after,
+// 回调名称为 "name:before_操作" 和 "name:after_操作"
+func registerCallbacks(db *gorm.DB, name string, before, after func(op string) func(*gorm.DB)) error {
+	callback := db.Callback()
+	processors := []struct {
+		op     string
+		before func(name string, fn func(*gorm.DB)) error
+		after  func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callback.Create().Before("gorm:create").Register, callback.Create().After("gorm:create").Register},
+		{"query", callback.Query().Before("gorm:query").Register, callback.Query().After("gorm:query").Register},
+		{"update", callback.Update().Before("gorm:update").Register, callback.Update().After("gorm:update").Register},
+		{"delete", callback.Delete().Before("gorm:delete").Register, callback.Delete().After("gorm:delete").Register},
+		{"row", callback.Row().Before("gorm:row").Register, callback.Row().After("gorm:row").Register},
+		{"raw", callback.Raw().Before("gorm:raw").Register, callback.Raw().After("gorm:raw").Register},
+	}
+	for _, v := range processors {
+		if err := v.before(name+":before_"+v.op, before(v.op)); err != nil {
+			return err
+		}
+		if err := v.after(name+":after_"+v.op, after(v.op)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *recorderPlugin) before(string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		db.InstanceSet(recorderStartKey, time.Now())
+	}
+}
+
+func (p *recorderPlugin) after(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(recorderStartKey)
+		if !ok {
+			return
+		}
+		begin, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+		err := db.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		p.recorder.Record(op, time.Since(begin), err)
+	}
+}
@@ -0,0 +1,66 @@
+package xorm
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const tracingSpanKey = "skuld:tracing:span"
+
+type tracingPlugin struct {
+	tracer trace.Tracer
+}
+
+// NewTracingPlugin 返回为每条 sql 创建 client span 的 gorm 插件, 名称为 "gorm 操作类型", db.statement 属性为 sql 语句,
+// span 的父节点来自 Core 方法传入的 ctx
+func NewTracingPlugin(tp trace.TracerProvider) gorm.Plugin {
+	return &tracingPlugin{tracer: tp.Tracer("skuld/xorm")}
+}
+
+func (p *tracingPlugin) Name() string {
+	return "skuld:tracing"
+}
+
+func (p *tracingPlugin) Initialize(db *gorm.DB) error {
+	return registerCallbacks(db, "skuld:tracing", p.before, p.after)
+}
+
+func (p *tracingPlugin) before(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := p.tracer.Start(db.Statement.Context, "gorm "+op,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("db.operation", op)),
+		)
+		if db.Statement.Table != "" {
+			span.SetAttributes(attribute.String("db.sql.table", db.Statement.Table))
+		}
+		db.Statement.Context = ctx
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+func (p *tracingPlugin) after(string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(tracingSpanKey)
+		if !ok {
+			return
+		}
+		span, ok := v.(trace.Span)
+		if !ok {
+			return
+		}
+		span.SetAttributes(
+			attribute.String("db.statement", db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.RowsAffected),
+		)
+		if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
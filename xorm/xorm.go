@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 
 	"skuld/xerr"
 )
@@ -70,6 +71,17 @@ func New(orm *gorm.DB) ORMItf {
 	return &ORM{orm: orm}
 }
 
+// Namer 返回 db 配置的命名策略, db 不是 New 创建时返回 gorm 默认的命名策略
+func Namer(db Core) schema.Namer {
+	switch v := db.(type) {
+	case *ORM:
+		return v.orm.NamingStrategy
+	case *TxORM:
+		return v.orm.NamingStrategy
+	}
+	return schema.NamingStrategy{}
+}
+
 func handleErr(err error) error {
 	if err == nil {
 		return nil
@@ -92,7 +104,12 @@ func (x *ORM) Tx(ctx context.Context, f func(context.Context) error) error {
 		return f(ctx)
 	}
 
-	tx := &TxORM{orm: x.orm.Begin()}
+	// 事务使用 ctx 开始, ctx 超时或取消时未提交的事务被回滚
+	db := x.orm.WithContext(ctx).Begin()
+	if db.Error != nil {
+		return handleErr(db.Error)
+	}
+	tx := &TxORM{orm: db}
 	return tx.Tx(func() error {
 		return f(context.WithValue(ctx, txkey{}, tx))
 	})
@@ -0,0 +1,70 @@
+package xsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"skuld/xlogger"
+)
+
+const (
+	logSilent = iota + 1
+	logError
+	logWarn
+	logInfo
+)
+
+type Option func(*DB)
+
+// WithLogger 将 sql 执行日志写入 l, level 为 silent / error / warn / info, 为空时使用 warn;
+// slowThreshold 小于等于 0 时不记录慢查询
+func WithLogger(l xlogger.Logger, level string, slowThreshold time.Duration) Option {
+	return func(d *DB) {
+		d.log = &queryLogger{
+			logger:        l,
+			level:         parseLogLevel(level),
+			slowThreshold: slowThreshold,
+		}
+	}
+}
+
+func parseLogLevel(level string) int {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logSilent
+	case "error":
+		return logError
+	case "info":
+		return logInfo
+	default:
+		return logWarn
+	}
+}
+
+type queryLogger struct {
+	logger        xlogger.Logger
+	level         int
+	slowThreshold time.Duration
+}
+
+func (l *queryLogger) trace(ctx context.Context, begin time.Time, query string, err error) {
+	if l == nil || l.level <= logSilent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	isErr := err != nil && !errors.Is(err, sql.ErrNoRows)
+	isSlow := l.slowThreshold > 0 && elapsed > l.slowThreshold
+	switch {
+	case isErr && l.level >= logError:
+		xlogger.WithContext(ctx, l.logger).Error("sql query error", "sql", query, "duration", elapsed.Milliseconds(), "err", err)
+	case isSlow && l.level >= logWarn:
+		xlogger.WithContext(ctx, l.logger).Warn("sql slow query", "sql", query, "duration", elapsed.Milliseconds(),
+			"slow_threshold", l.slowThreshold.Milliseconds())
+	case l.level >= logInfo:
+		xlogger.WithContext(ctx, l.logger).Info("sql query", "sql", query, "duration", elapsed.Milliseconds())
+	}
+}
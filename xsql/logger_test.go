@@ -0,0 +1,63 @@
+package xsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"skuld/requestid"
+	"skuld/xlogger/xloggertest"
+)
+
+func TestQueryLogger(t *testing.T) {
+	ctx := requestid.NewContext(context.Background(), "rid")
+	fast := time.Now()
+	slow := time.Now().Add(-time.Second)
+	boom := errors.New("boom")
+
+	type testCase struct {
+		name      string
+		level     string
+		threshold time.Duration
+		begin     time.Time
+		err       error
+		expect    xloggertest.Level
+		msg       string
+	}
+	testTable := []testCase{
+		{name: "error", level: "error", begin: fast, err: boom, expect: xloggertest.ErrorLevel, msg: "sql query error"},
+		{name: "error silent", level: "silent", begin: fast, err: boom},
+		{name: "no rows ignored", level: "warn", begin: fast, err: sql.ErrNoRows},
+		{name: "slow", level: "", begin: slow, expect: xloggertest.WarnLevel, msg: "sql slow query"},
+		{name: "slow filtered", level: "error", begin: slow},
+		{name: "slow disabled", level: "warn", threshold: -1, begin: slow},
+		{name: "query", level: "info", begin: fast, expect: xloggertest.InfoLevel, msg: "sql query"},
+		{name: "query filtered", level: "warn", begin: fast},
+	}
+
+	for _, v := range testTable {
+		t.Run(v.name, func(t *testing.T) {
+			l := xloggertest.NewT(t)
+			l.ExpectError("sql query error")
+			threshold := 100 * time.Millisecond
+			if v.threshold != 0 {
+				threshold = v.threshold
+			}
+			d := New(nil, WithLogger(l, v.level, threshold))
+			d.log.trace(ctx, v.begin, "SELECT 1", v.err)
+
+			if v.msg == "" {
+				if entries := l.Entries(); len(entries) != 0 {
+					t.Fatalf("expect nothing logged, but get %v", entries)
+				}
+				return
+			}
+			l.AssertLogged(t, v.expect, v.msg, "sql", "SELECT 1", "request_id", "rid")
+		})
+	}
+
+	// 未设置 WithLogger 时不输出
+	New(nil).log.trace(ctx, slow, "SELECT 1", boom)
+}
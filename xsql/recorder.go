@@ -0,0 +1,45 @@
+package xsql
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Recorder 记录每条 sql 的操作类型 (select / insert / update / delete / replace / other), 耗时和结果,
+// 可以实现该接口将数据导出为监控指标, sql.ErrNoRows 不视为错误
+type Recorder interface {
+	Record(op string, d time.Duration, err error)
+}
+
+// WithRecorder 将每条 sql 的执行结果交给 r
+func WithRecorder(r Recorder) Option {
+	return func(d *DB) {
+		d.rec = r
+	}
+}
+
+func record(r Recorder, begin time.Time, query string, err error) {
+	if r == nil {
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	r.Record(operation(query), time.Since(begin), err)
+}
+
+// operation 返回 sql 的第一个关键字作为操作类型
+func operation(query string) string {
+	query = strings.TrimSpace(query)
+	if i := strings.IndexAny(query, " \t\r\n("); i > 0 {
+		query = query[:i]
+	}
+	switch op := strings.ToLower(query); op {
+	case "select", "insert", "update", "delete", "replace":
+		return op
+	default:
+		return "other"
+	}
+}
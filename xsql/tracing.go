@@ -0,0 +1,37 @@
+package xsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTracerProvider 为每条 sql 创建 client span, 名称为 "sql 操作类型", db.statement 属性为 sql 语句
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(d *DB) {
+		d.tracer = tp.Tracer("skuld/xsql")
+	}
+}
+
+func startSpan(tracer trace.Tracer, ctx context.Context, query string) (context.Context, trace.Span) {
+	op := operation(query)
+	return tracer.Start(ctx, "sql "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.operation", op),
+			attribute.String("db.statement", query),
+		),
+	)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,120 @@
+package xsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeDriver 每次查询返回 n 行的驱动, 用于测试
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not implemented") }
+
+func (fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	n := 2
+	if query == "SELECT empty" {
+		n = 0
+	}
+	return &fakeRows{n: n}, nil
+}
+
+type fakeRows struct {
+	n int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"id"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n == 0 {
+		return io.EOF
+	}
+	dest[0] = int64(r.n)
+	r.n--
+	return nil
+}
+
+func init() {
+	sql.Register("xsql_fake", fakeDriver{})
+}
+
+func TestQuerySpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tp.Shutdown(context.Background())
+
+	raw, err := sql.Open("xsql_fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	db := New(raw, WithTracerProvider(tp))
+	ctx := context.Background()
+
+	// span 在遍历完所有行后结束
+	rows, err := db.Query(ctx, "SELECT id FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Fatalf("expect span not ended before rows iterated, but get %d spans", n)
+	}
+	count := 0
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("expect 2 rows, but get %d", count)
+	}
+	_ = rows.Close()
+	if spans := exporter.GetSpans(); len(spans) != 1 || spans[0].Name != "sql select" {
+		t.Fatalf("expect 1 sql select span after rows iterated, but get %v", spans)
+	}
+
+	// 提前 Close 时结束 span
+	exporter.Reset()
+	rows, err = db.Query(ctx, "SELECT id FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Next()
+	_ = rows.Close()
+	if n := len(exporter.GetSpans()); n != 1 {
+		t.Errorf("expect span ended on close, but get %d spans", n)
+	}
+
+	// QueryRow 的 span 在 Scan 后结束, 没有数据不视为错误
+	exporter.Reset()
+	row, err := db.QueryRow(ctx, "SELECT empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Fatalf("expect span not ended before scan, but get %d spans", n)
+	}
+	var id int64
+	if err = row.Scan(&id); err == nil {
+		t.Fatal("expect no data error")
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || len(spans[0].Events) != 0 {
+		t.Errorf("expect 1 span without error, but get %v", spans)
+	}
+}
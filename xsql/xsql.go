@@ -4,12 +4,40 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 
 	"skuld/xerr"
 )
 
+// Rows 链路追踪的 span 在遍历结束或 Close 时结束, 包含读取数据的耗时
 type Rows struct {
 	*sql.Rows
+	span trace.Span
+}
+
+func (r *Rows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.endSpan(r.Rows.Err())
+	return false
+}
+
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	r.endSpan(err)
+	return err
+}
+
+func (r *Rows) endSpan(err error) {
+	if r.span == nil {
+		return
+	}
+	endSpan(r.span, err)
+	r.span = nil
 }
 
 func (r *Rows) Scan(desc ...interface{}) error {
@@ -22,12 +50,19 @@ func (r *Rows) Scan(desc ...interface{}) error {
 	return nil
 }
 
+// Row 链路追踪的 span 在 Scan 时结束, 包含读取数据的耗时
 type Row struct {
 	*sql.Row
+	span trace.Span
 }
 
 func (r *Row) Scan(desc ...interface{}) error {
-	if err := r.Row.Scan(desc...); err != nil {
+	err := r.Row.Scan(desc...)
+	if r.span != nil {
+		endSpan(r.span, err)
+		r.span = nil
+	}
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return xerr.NoData
 		}
@@ -55,13 +90,20 @@ type TxItf interface {
 }
 
 type DB struct {
-	db *sql.DB
+	db     *sql.DB
+	log    *queryLogger
+	rec    Recorder
+	tracer trace.Tracer
 }
 
 type txkey struct{}
 
-func New(db *sql.DB) *DB {
-	return &DB{db: db}
+func New(db *sql.DB, opts ...Option) *DB {
+	d := &DB{db: db, tracer: noop.NewTracerProvider().Tracer("")}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
 }
 
 func (d *DB) tx(ctx context.Context) TxItf {
@@ -76,7 +118,13 @@ func (d *DB) Exec(ctx context.Context, query string, args ...interface{}) (sql.R
 		return tx.Exec(ctx, query, args...)
 	}
 
-	return d.db.ExecContext(ctx, query, args...)
+	begin := time.Now()
+	ctx, span := startSpan(d.tracer, ctx, query)
+	rst, err := d.db.ExecContext(ctx, query, args...)
+	endSpan(span, err)
+	d.log.trace(ctx, begin, query, err)
+	record(d.rec, begin, query, err)
+	return rst, err
 }
 
 func (d *DB) Query(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
@@ -84,11 +132,16 @@ func (d *DB) Query(ctx context.Context, query string, args ...interface{}) (*Row
 		return tx.Query(ctx, query, args...)
 	}
 
+	begin := time.Now()
+	ctx, span := startSpan(d.tracer, ctx, query)
 	rows, err := d.db.QueryContext(ctx, query, args...)
+	d.log.trace(ctx, begin, query, err)
+	record(d.rec, begin, query, err)
 	if err != nil {
+		endSpan(span, err)
 		return nil, err
 	}
-	return &Rows{rows}, nil
+	return &Rows{Rows: rows, span: span}, nil
 }
 
 func (d *DB) QueryRow(ctx context.Context, query string, args ...interface{}) (*Row, error) {
@@ -96,11 +149,16 @@ func (d *DB) QueryRow(ctx context.Context, query string, args ...interface{}) (*
 		return tx.QueryRow(ctx, query, args...)
 	}
 
+	begin := time.Now()
+	ctx, span := startSpan(d.tracer, ctx, query)
 	row := d.db.QueryRowContext(ctx, query, args...)
+	d.log.trace(ctx, begin, query, row.Err())
+	record(d.rec, begin, query, row.Err())
 	if err := row.Err(); err != nil {
+		endSpan(span, err)
 		return nil, err
 	}
-	return &Row{row}, nil
+	return &Row{Row: row, span: span}, nil
 }
 
 func (d *DB) Tx(ctx context.Context, f func(context.Context) error) error {
@@ -111,36 +169,55 @@ func (d *DB) Tx(ctx context.Context, f func(context.Context) error) error {
 
 	rawTx, err := d.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil
+		return err
 	}
-	tx = &Tx{tx: rawTx}
+	tx = &Tx{tx: rawTx, log: d.log, rec: d.rec, tracer: d.tracer}
 	return tx.Tx(func() error {
 		return f(context.WithValue(ctx, txkey{}, tx))
 	})
 }
 
 type Tx struct {
-	tx *sql.Tx
+	tx     *sql.Tx
+	log    *queryLogger
+	rec    Recorder
+	tracer trace.Tracer
 }
 
 func (t *Tx) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return t.tx.ExecContext(ctx, query, args...)
+	begin := time.Now()
+	ctx, span := startSpan(t.tracer, ctx, query)
+	rst, err := t.tx.ExecContext(ctx, query, args...)
+	endSpan(span, err)
+	t.log.trace(ctx, begin, query, err)
+	record(t.rec, begin, query, err)
+	return rst, err
 }
 
 func (t *Tx) Query(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	begin := time.Now()
+	ctx, span := startSpan(t.tracer, ctx, query)
 	rows, err := t.tx.QueryContext(ctx, query, args...)
+	t.log.trace(ctx, begin, query, err)
+	record(t.rec, begin, query, err)
 	if err != nil {
+		endSpan(span, err)
 		return nil, err
 	}
-	return &Rows{rows}, nil
+	return &Rows{Rows: rows, span: span}, nil
 }
 
 func (t *Tx) QueryRow(ctx context.Context, query string, args ...interface{}) (*Row, error) {
+	begin := time.Now()
+	ctx, span := startSpan(t.tracer, ctx, query)
 	row := t.tx.QueryRowContext(ctx, query, args...)
+	t.log.trace(ctx, begin, query, row.Err())
+	record(t.rec, begin, query, row.Err())
 	if err := row.Err(); err != nil {
+		endSpan(span, err)
 		return nil, err
 	}
-	return &Row{row}, nil
+	return &Row{Row: row, span: span}, nil
 }
 
 func (t *Tx) Rollback() error {